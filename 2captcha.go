@@ -2,6 +2,8 @@ package captchaAIO
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -18,15 +20,27 @@ import (
 
 func NewTwoCaptchaClient(key string) *TwoCaptcha {
 	return &TwoCaptcha{
-		Key:  key,
-		http: &http.Client{},
+		Key:          key,
+		BaseURL:      twoCaptchaURL,
+		FirstPoll:    time.Duration(20) * time.Second,
+		PollInterval: time.Duration(5) * time.Second,
+		http:         &http.Client{},
 	}
 }
 
+const twoCaptchaURL = "https://2captcha.com"
+
 type TwoCaptcha struct {
 	Key    string
 	SoftID string
-	http   *http.Client
+	// BaseURL is the address in.php and res.php are requested from,
+	// https://2captcha.com unless pointed at a compatible service or a fake.
+	BaseURL string
+	// FirstPoll is how long Solve waits before polling a task for the first
+	// time and PollInterval how long it waits between polls.
+	FirstPoll    time.Duration
+	PollInterval time.Duration
+	http         *http.Client
 }
 
 func (tc *TwoCaptcha) logf(f string, v ...interface{}) {
@@ -41,43 +55,84 @@ func (tc *TwoCaptcha) SetTimeout(t time.Duration) {
 
 var ErrUnsupportedCaptcha = errors.New("captchaAIO: could not identify given captcha type")
 
-func (tc *TwoCaptcha) Solve(captcha interface{}, proxy string) (string, error) {
+func (tc *TwoCaptcha) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return tc.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext sends the captcha and polls for its result until it is solved,
+// the service returns an error, or ctx is done.
+func (tc *TwoCaptcha) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
 	tc.logf("Starting")
-	id, err := tc.Send(captcha, proxy)
+	start := time.Now()
+	id, err := tc.SendContext(ctx, captcha, proxy)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	tc.logf("Solving task %v", id)
-	time.Sleep(time.Duration(20) * time.Second)
-	for {
-		result, err := tc.GetRes(id)
-		if err != nil {
-			if errors.Is(err, ErrCaptchaNotReady) {
-				tc.logf("captcha not ready, waiting 5 seconds")
-				time.Sleep(time.Duration(5) * time.Second)
-				continue
-			}
-			tc.logf(err.Error())
-			return "", err
-		}
-		tc.logf("Result: %v", result)
-		return result[3:], nil
+	sol, err := poll(ctx, tc.FirstPoll, tc.PollInterval, func() (*Solution, error) {
+		return tc.GetResContext(ctx, id)
+	})
+	if err != nil {
+		tc.logf(err.Error())
+		return nil, err
+	}
+	tc.logf("Result: %s", sol.Token)
+	if err := tc.decode(captcha, sol); err != nil {
+		return nil, err
 	}
+	sol.Duration = time.Since(start)
+	return sol, nil
 }
 
 // Send sends a captcha task to be solved by twocaptcha, will return the id of the task
 // or an error
 func (tc *TwoCaptcha) Send(captcha interface{}, proxy string) (string, error) {
-	var req TwoCaptchaRequest
+	return tc.SendContext(context.Background(), captcha, proxy)
+}
+
+// SendContext is like Send, but aborts the submission once ctx is done.
+func (tc *TwoCaptcha) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	var (
+		req TwoCaptchaRequest
+		err error
+	)
 	switch v := captcha.(type) {
 	case ReCaptcha:
 		req = tc.reCaptcha(v)
 	case HCaptcha:
 		req = tc.hCaptcha(v)
+	case Normal:
+		req, err = tc.normal(v)
+	case FunCaptcha:
+		req = tc.funCaptcha(v)
+	case GeeTest:
+		req = tc.geeTest(v)
+	case GeeTestV4:
+		req = tc.geeTestV4(v)
+	case Turnstile:
+		req = tc.turnstile(v)
+	case Grid:
+		req = tc.grid(v)
+	case Coordinates:
+		req = tc.coordinates(v)
+	case Rotate:
+		req = tc.rotate(v)
+	case Canvas:
+		req, err = tc.canvas(v)
+	case Capy:
+		req = tc.capy(v)
+	case KeyCaptcha:
+		req = tc.keyCaptcha(v)
+	case Text:
+		req = tc.text(v)
 	default:
 		return "", ErrUnsupportedCaptcha
 	}
+	if err != nil {
+		return "", err
+	}
 	req.Params["key"] = tc.Key
+	req.Params["json"] = "1"
 	tc.logf("%v", req)
 
 	if proxy != "" {
@@ -123,7 +178,11 @@ func (tc *TwoCaptcha) Send(captcha interface{}, proxy string) (string, error) {
 			}
 		}
 
-		req, err := http.NewRequest("POST", "https://2captcha.com/in.php", body)
+		if err := w.Close(); err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "POST", tc.BaseURL+"/in.php", body)
 		if err != nil {
 			return "", ErrNetwork
 		}
@@ -138,12 +197,17 @@ func (tc *TwoCaptcha) Send(captcha interface{}, proxy string) (string, error) {
 			form.Add(k, v)
 		}
 
-		r, err := http.NewRequest("POST", "https://2captcha.com/in.php", nil)
+		// Sent as the body rather than the query, base64 images are too
+		// long for a URL
+		r, err := http.NewRequestWithContext(ctx, "POST", tc.BaseURL+"/in.php", strings.NewReader(form.Encode()))
 		if err != nil {
 			return "", err
 		}
-		r.URL.RawQuery = form.Encode()
+		r.Header.Set("content-type", "application/x-www-form-urlencoded")
 		resp, err = tc.http.Do(r)
+		if err != nil {
+			return "", err
+		}
 	}
 	defer resp.Body.Close()
 
@@ -151,120 +215,278 @@ func (tc *TwoCaptcha) Send(captcha interface{}, proxy string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	data := string(body)
-	tc.logf("task submission request returned data %v", data)
-	if strings.HasPrefix(data, "ERROR") {
-		switch data {
-		case "ERROR_WRONG_USER_KEY":
-			return "", ErrWrongUserKey
-		case "ERROR_KEY_DOES_NOT_EXIST":
-			return "", ErrKeyDoesNotExist
-		case "ERROR_ZERO_BALANCE":
-			return "", ErrZeroBalance
-		case "ERROR_PAGEURL":
-			return "", ErrPageURL
-		case "ERROR_NO_SLOT_AVAILABLE":
-			return "", ErrNoSlotAvailable
-		case "ERROR_ZERO_CAPTCHA_FILESIZE":
-			return "", ErrZeroCaptchaFilesize
-		case "ERROR_TOO_BIG_CAPTCHA_FILESIZE":
-			return "", ErrTooBigCaptcha
-		case "ERROR_WRONG_FILE_EXTENSION":
-			return "", ErrWrongFileExtension
-		case "ERROR_IMAGE_TYPE_NOT_SUPPORTED":
-			return "", ErrImageTypeNotSupported
-		case "ERROR_UPLOAD":
-			return "", ErrUpload
-		case "ERROR_IP_NOT_ALLOWED":
-			return "", ErrIPNotAllowed
-		case "IP_BANNED":
-			return "", ErrIPBanned
-		case "ERROR_BAD_TOKEN_OR_PAGEURL":
-			return "", ErrBadTokenOrPageURL
-		case "ERROR_GOOGLEKEY", "ERROR_SITEKEY":
-			return "", ErrGoogleKey
-		case "ERROR_WRONG_GOOGLEKEY":
-			return "", ErrGoogleKey
-		case "ERROR_CAPTCHAIMAGE_BLOCKED":
-			return "", ErrCaptchaImageBlocked
-		case "TOO_MANY_BAD_IMAGES":
-			return "", ErrTooManyBadImages
-		case "MAX_USER_TURN":
-			return "", ErrMaxUserTurn
-		case "ERROR: NNNN":
-			return "", ErrTooManyRequests
-		case "ERROR_BAD_PARAMETERS":
-			return "", ErrBadParameters
-		case "ERROR_BAD_PROXY":
-			return "", ErrProxyConnFail
-		default:
-			return "", ErrUnknown
-		}
+	tc.logf("task submission request returned data %s", body)
+	var res twoCaptchaResponse
+	err = json.Unmarshal(body, &res)
+	if resp.StatusCode/100 != 2 && (err != nil || res.Request == nil) {
+		return "", statusError(resp)
 	}
+	if err != nil {
+		return "", err
+	}
+	if res.Status != 1 {
+		return "", twoCaptchaError(res.text())
+	}
+	return res.text(), nil
+}
 
-	return data[3:], nil
+// GetRes fetches the result of the task with the given id, returning
+// ErrCaptchaNotReady while a worker is still solving it.
+func (tc *TwoCaptcha) GetRes(id string) (*Solution, error) {
+	return tc.GetResContext(context.Background(), id)
 }
 
-func (tc *TwoCaptcha) GetRes(id string) (string, error) {
-	req, err := http.NewRequest("GET", "https://2captcha.com/res.php", nil)
+// GetResContext is like GetRes, but aborts the request once ctx is done.
+func (tc *TwoCaptcha) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	res, err := tc.getRes(ctx, id)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return res.solution(id), nil
+}
+
+func (tc *TwoCaptcha) getRes(ctx context.Context, id string) (*twoCaptchaResponse, error) {
 	q := url.Values{}
-	q.Add("key", tc.Key)
-	q.Add("action", "get")
+	q.Add("action", "get2")
 	q.Add("id", id)
+	return tc.res(ctx, q)
+}
+
+// res requests res.php with the query q and returns the JSON response, or the
+// error it reports.
+func (tc *TwoCaptcha) res(ctx context.Context, q url.Values) (*twoCaptchaResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", tc.BaseURL+"/res.php", nil)
+	if err != nil {
+		return nil, err
+	}
+	q.Set("key", tc.Key)
+	q.Set("json", "1")
 	req.URL.RawQuery = q.Encode()
 	resp, err := tc.http.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	switch string(body) {
+	var res twoCaptchaResponse
+	err = json.Unmarshal(body, &res)
+	if resp.StatusCode/100 != 2 && (err != nil || res.Request == nil) {
+		return nil, statusError(resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != 1 {
+		return nil, twoCaptchaError(res.text())
+	}
+	return &res, nil
+}
+
+// twoCaptchaResponse is the body returned by in.php and res.php when json=1
+// is set. Request holds the task id, the answer, or an error code.
+type twoCaptchaResponse struct {
+	Status    int             `json:"status"`
+	Request   json.RawMessage `json:"request"`
+	UserAgent string          `json:"useragent"`
+	RespKey   string          `json:"respKey"`
+	Price     string          `json:"price"`
+}
+
+// text returns Request as a plain string, unquoting it if it is a JSON string.
+func (r *twoCaptchaResponse) text() string {
+	var s string
+	if err := json.Unmarshal(r.Request, &s); err != nil {
+		return string(r.Request)
+	}
+	return s
+}
+
+func (r *twoCaptchaResponse) solution(id string) *Solution {
+	sol := &Solution{
+		Token:     r.text(),
+		TaskID:    id,
+		Provider:  "2captcha",
+		UserAgent: r.UserAgent,
+		RespKey:   r.RespKey,
+	}
+	if r.Price != "" {
+		sol.Cost, _ = strconv.ParseFloat(r.Price, 64)
+	}
+	return sol
+}
+
+// decode fills in the type specific fields of sol from the answer to
+// captcha, which GetRes leaves in Token as returned by 2Captcha.
+func (*TwoCaptcha) decode(captcha interface{}, sol *Solution) error {
+	var err error
+	switch captcha.(type) {
+	case GeeTest:
+		sol.GeeTest = &GeeTestSolution{}
+		err = json.Unmarshal([]byte(sol.Token), sol.GeeTest)
+	case GeeTestV4:
+		sol.GeeTestV4 = &GeeTestV4Solution{}
+		err = json.Unmarshal([]byte(sol.Token), sol.GeeTestV4)
+	case Capy:
+		sol.Capy = &CapySolution{}
+		err = json.Unmarshal([]byte(sol.Token), sol.Capy)
+	case Grid:
+		sol.Cells, err = gridCells(sol.Token)
+	case Coordinates:
+		sol.Points, err = coordinatePoints(sol.Token)
+	case Canvas:
+		sol.Lines, err = canvasLines(sol.Token)
+	case Rotate:
+		sol.Angles, err = rotateAngles(sol.Token)
+		if err == nil {
+			sol.Angle = sol.Angles[0]
+		}
+	}
+	return err
+}
+
+// canvasLines parses a canvas answer such as canvas:[[[66,142],[78,159]]],
+// a list of lines each given as [x, y] pairs. The answer may also be the
+// JSON list itself, or a single line.
+func canvasLines(answer string) ([][]Point, error) {
+	request := []byte(strings.TrimPrefix(answer, "canvas:"))
+	var lines [][][2]int
+	if err := json.Unmarshal(request, &lines); err != nil {
+		var line [][2]int
+		if err := json.Unmarshal(request, &line); err != nil {
+			return nil, fmt.Errorf("captchaAIO: unexpected canvas answer %s", request)
+		}
+		lines = [][][2]int{line}
+	}
+	points := make([][]Point, len(lines))
+	for i, line := range lines {
+		points[i] = make([]Point, len(line))
+		for j, p := range line {
+			points[i][j] = Point{X: p[0], Y: p[1]}
+		}
+	}
+	return points, nil
+}
+
+// rotateAngles parses a rotate answer, the angle of each image separated by
+// "|" when several were sent.
+func rotateAngles(answer string) ([]int, error) {
+	var angles []int
+	for _, s := range strings.Split(answer, "|") {
+		angle, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("captchaAIO: unexpected rotate answer %q", answer)
+		}
+		angles = append(angles, angle)
+	}
+	return angles, nil
+}
+
+// gridCells parses a grid answer such as click:1/4/7 into cell indexes.
+func gridCells(answer string) ([]int, error) {
+	if answer == "No_matching_images" {
+		return nil, nil
+	}
+	answer = strings.TrimPrefix(answer, "click:")
+	if answer == "" {
+		return nil, nil
+	}
+	var cells []int
+	for _, s := range strings.Split(answer, "/") {
+		cell, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, fmt.Errorf("captchaAIO: unexpected grid answer %q", answer)
+		}
+		cells = append(cells, cell)
+	}
+	return cells, nil
+}
+
+// twoCaptchaError maps an error code returned by in.php or res.php to one of
+// the package's errors.
+func twoCaptchaError(code string) error {
+	switch code {
 	case "CAPCHA_NOT_READY":
-		return "", ErrCaptchaNotReady
-	case "ERROR_CAPTCHA_UNSOLVABLE":
-		return "", ErrCaptchaUnsolvable
+		return ErrCaptchaNotReady
+	case "ERROR_WRONG_USER_KEY":
+		return ErrWrongUserKey
 	case "ERROR_KEY_DOES_NOT_EXIST":
-		return "", ErrKeyDoesNotExist
+		return ErrKeyDoesNotExist
+	case "ERROR_ZERO_BALANCE":
+		return ErrZeroBalance
+	case "ERROR_PAGEURL":
+		return ErrPageURL
+	case "ERROR_NO_SLOT_AVAILABLE":
+		return ErrNoSlotAvailable
+	case "ERROR_ZERO_CAPTCHA_FILESIZE":
+		return ErrZeroCaptchaFilesize
+	case "ERROR_TOO_BIG_CAPTCHA_FILESIZE":
+		return ErrTooBigCaptcha
+	case "ERROR_WRONG_FILE_EXTENSION":
+		return ErrWrongFileExtension
+	case "ERROR_IMAGE_TYPE_NOT_SUPPORTED":
+		return ErrImageTypeNotSupported
+	case "ERROR_UPLOAD":
+		return ErrUpload
+	case "ERROR_IP_NOT_ALLOWED", "ERROR_IP_ADDRES":
+		return ErrIPNotAllowed
+	case "IP_BANNED":
+		return ErrIPBanned
+	case "ERROR_BAD_TOKEN_OR_PAGEURL":
+		return ErrBadTokenOrPageURL
+	case "ERROR_GOOGLEKEY", "ERROR_SITEKEY", "ERROR_WRONG_GOOGLEKEY":
+		return ErrGoogleKey
+	case "ERROR_CAPTCHAIMAGE_BLOCKED":
+		return ErrCaptchaImageBlocked
+	case "TOO_MANY_BAD_IMAGES":
+		return ErrTooManyBadImages
+	case "MAX_USER_TURN":
+		return ErrMaxUserTurn
+	case "ERROR: NNNN":
+		return ErrTooManyRequests
+	case "ERROR_BAD_PARAMETERS":
+		return ErrBadParameters
+	case "ERROR_BAD_PROXY", "ERROR_PROXY_CONNECTION_FAILED":
+		return ErrProxyConnFail
+	case "ERROR_CAPTCHA_UNSOLVABLE":
+		return ErrCaptchaUnsolvable
 	case "ERROR_WRONG_ID_FORMAT":
-		return "", ErrWrongIDFormat
+		return ErrWrongIDFormat
 	case "ERROR_WRONG_CAPTCHA_ID":
-		return "", ErrWrongCaptchaID
+		return ErrWrongCaptchaID
 	case "ERROR_BAD_DUPLICATES":
-		return "", ErrBadDuplicates
-	case "ERROR: NNNN":
-		return "", ErrTooManyRequests
-	case "ERROR_IP_ADDRES":
-		return "", ErrIPNotAllowed
+		return ErrBadDuplicates
 	case "ERROR_TOKEN_EXPIRED":
-		return "", ErrTokenExpired
+		return ErrTokenExpired
 	case "ERROR_EMPTY_ACTION":
-		return "", ErrEmptyAction
-	case "ERROR_PROXY_CONNECTION_FAILED":
-		return "", ErrProxyConnFail
+		return ErrEmptyAction
 	default:
-		return string(body), nil
+		return ErrUnknown
 	}
 }
 
 func (tc *TwoCaptcha) Report(id string, correct bool) error {
-	req, err := http.NewRequest("GET", "https://2captcha.com/res.php", nil)
+	return tc.ReportContext(context.Background(), id, correct)
+}
+
+// ReportContext is like Report, but aborts the request once ctx is done.
+func (tc *TwoCaptcha) ReportContext(ctx context.Context, id string, correct bool) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", tc.BaseURL+"/res.php", nil)
 	if err != nil {
 		return err
 	}
 	q := url.Values{}
+	q.Add("key", tc.Key)
 	if correct {
 		q.Add("action", "reportgood")
 	} else {
 		q.Add("action", "reportbad")
 	}
 	q.Add("id", id)
+	req.URL.RawQuery = q.Encode()
 	resp, err := tc.http.Do(req)
 	if err != nil {
 		return err
@@ -287,24 +509,18 @@ func (tc *TwoCaptcha) Report(id string, correct bool) error {
 }
 
 func (tc *TwoCaptcha) GetBalance() (float64, error) {
-	req, err := http.NewRequest("GET", "https://2captcha.com/res.php", nil)
-	if err != nil {
-		return 0, err
-	}
+	return tc.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance, but aborts the request once ctx is done.
+func (tc *TwoCaptcha) GetBalanceContext(ctx context.Context) (float64, error) {
 	q := url.Values{}
-	q.Add("key", tc.Key)
 	q.Add("action", "getbalance")
-	req.URL.RawQuery = q.Encode()
-	resp, err := tc.http.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	res, err := tc.res(ctx, q)
 	if err != nil {
 		return 0, err
 	}
-	bal, err := strconv.ParseFloat(string(body), 64)
+	bal, err := strconv.ParseFloat(res.text(), 64)
 	if err != nil {
 		return 0, err
 	}
@@ -340,6 +556,18 @@ func (*TwoCaptcha) reCaptcha(c ReCaptcha) TwoCaptchaRequest {
 	if c.Score != 0 {
 		req.Params["min_score"] = strconv.FormatFloat(c.Score, 'f', -1, 64)
 	}
+	if c.Enterprise {
+		req.Params["enterprise"] = "1"
+	}
+	if c.S != "" {
+		req.Params["data-s"] = c.S
+	}
+	if c.ApiDomain != "" {
+		req.Params["domain"] = c.ApiDomain
+	}
+	if c.UserAgent != "" {
+		req.Params["userAgent"] = c.UserAgent
+	}
 
 	return req
 }
@@ -356,5 +584,364 @@ func (*TwoCaptcha) hCaptcha(c HCaptcha) TwoCaptchaRequest {
 	if c.PageUrl != "" {
 		req.Params["pageurl"] = c.PageUrl
 	}
+	if c.Invisible {
+		req.Params["invisible"] = "1"
+	}
+	if c.RqData != "" {
+		req.Params["data"] = c.RqData
+	}
+	if c.UserAgent != "" {
+		req.Params["userAgent"] = c.UserAgent
+	}
+	if c.ApiDomain != "" {
+		req.Params["domain"] = c.ApiDomain
+	}
+	return req
+}
+
+// funCaptcha builds a method=funcaptcha request. Each entry of c.Data is
+// sent as its own data[key] parameter, e.g. data[blob].
+func (*TwoCaptcha) funCaptcha(c FunCaptcha) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "funcaptcha",
+		},
+	}
+	if c.SiteKey != "" {
+		req.Params["publickey"] = c.SiteKey
+	}
+	if c.Url != "" {
+		req.Params["pageurl"] = c.Url
+	}
+	if c.Surl != "" {
+		req.Params["surl"] = c.Surl
+	}
+	if c.UserAgent != "" {
+		req.Params["userAgent"] = c.UserAgent
+	}
+	for k, v := range c.Data {
+		req.Params["data["+k+"]"] = v
+	}
+	return req
+}
+
+func (*TwoCaptcha) geeTest(c GeeTest) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "geetest",
+		},
+	}
+	if c.GT != "" {
+		req.Params["gt"] = c.GT
+	}
+	if c.Challenge != "" {
+		req.Params["challenge"] = c.Challenge
+	}
+	if c.ApiServer != "" {
+		req.Params["api_server"] = c.ApiServer
+	}
+	if c.Url != "" {
+		req.Params["pageurl"] = c.Url
+	}
+	return req
+}
+
+// geeTestV4 builds a method=geetest_v4 request. Extra init parameters are
+// sent as initParameters[key].
+func (*TwoCaptcha) geeTestV4(c GeeTestV4) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method":  "geetest_v4",
+			"version": "4",
+		},
+	}
+	if c.CaptchaID != "" {
+		req.Params["captcha_id"] = c.CaptchaID
+	}
+	if c.Url != "" {
+		req.Params["pageurl"] = c.Url
+	}
+	if c.ApiServer != "" {
+		req.Params["api_server"] = c.ApiServer
+	}
+	if c.RiskType != "" {
+		req.Params["initParameters[risk_type]"] = c.RiskType
+	}
+	for k, v := range c.Extra {
+		req.Params["initParameters["+k+"]"] = v
+	}
+	return req
+}
+
+func (*TwoCaptcha) capy(c Capy) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "capy",
+		},
+	}
+	if c.SiteKey != "" {
+		req.Params["captchakey"] = c.SiteKey
+	}
+	if c.Url != "" {
+		req.Params["pageurl"] = c.Url
+	}
+	if c.ApiServer != "" {
+		req.Params["api_server"] = c.ApiServer
+	}
+	return req
+}
+
+func (*TwoCaptcha) keyCaptcha(c KeyCaptcha) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "keycaptcha",
+		},
+	}
+	if c.UserId != 0 {
+		req.Params["s_s_c_user_id"] = strconv.Itoa(c.UserId)
+	}
+	if c.SessionId != "" {
+		req.Params["s_s_c_session_id"] = c.SessionId
+	}
+	if c.WebServerSign != "" {
+		req.Params["s_s_c_web_server_sign"] = c.WebServerSign
+	}
+	if c.WebServerSign2 != "" {
+		req.Params["s_s_c_web_server_sign2"] = c.WebServerSign2
+	}
+	if c.Url != "" {
+		req.Params["pageurl"] = c.Url
+	}
+	return req
+}
+
+// text builds a textcaptcha request, answered with the answer to the
+// question in c.Text.
+func (*TwoCaptcha) text(c Text) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"textcaptcha": c.Text,
+		},
+	}
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	return req
+}
+
+func (*TwoCaptcha) turnstile(c Turnstile) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "turnstile",
+		},
+	}
+	if c.SiteKey != "" {
+		req.Params["sitekey"] = c.SiteKey
+	}
+	if c.PageUrl != "" {
+		req.Params["pageurl"] = c.PageUrl
+	}
+	if c.Action != "" {
+		req.Params["action"] = c.Action
+	}
+	if c.CData != "" {
+		req.Params["data"] = c.CData
+	}
+	if c.PageData != "" {
+		req.Params["pagedata"] = c.PageData
+	}
+	if c.UserAgent != "" {
+		req.Params["userAgent"] = c.UserAgent
+	}
+	return req
+}
+
+// normal builds a method=post request uploading c.File, or a method=base64
+// request when the image is given in memory.
+func (tc *TwoCaptcha) normal(c Normal) (TwoCaptchaRequest, error) {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{},
+		Files:  map[string]string{},
+	}
+	if c.File != "" && c.Base64 == "" && c.Image == nil && c.Reader == nil {
+		req.Params["method"] = "post"
+		req.Files["file"] = c.File
+	} else {
+		body, err := normalBase64(c)
+		if err != nil {
+			return req, err
+		}
+		req.Params["method"] = "base64"
+		req.Params["body"] = body
+	}
+	if c.Phrase {
+		req.Params["phrase"] = "1"
+	}
+	if c.CaseSensitive {
+		req.Params["regsense"] = "1"
+	}
+	if c.Calc {
+		req.Params["calc"] = "1"
+	}
+	if c.Numberic != 0 {
+		req.Params["numeric"] = strconv.Itoa(c.Numberic)
+	}
+	if c.MinLen != 0 {
+		req.Params["min_len"] = strconv.Itoa(c.MinLen)
+	}
+	if c.MaxLen != 0 {
+		req.Params["max_len"] = strconv.Itoa(c.MaxLen)
+	}
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	tc.instructions(&req, c.HintText, c.HintImageBase64, c.HintImageFile)
+	return req, nil
+}
+
+// coordinatePoints parses a coordinates answer, either the JSON list of
+// {"x": "52", "y": "88"} objects or the coordinates:x=52,y=88;... text form.
+func coordinatePoints(answer string) ([]Point, error) {
+	var list []struct {
+		X json.Number `json:"x"`
+		Y json.Number `json:"y"`
+	}
+	if err := json.Unmarshal([]byte(answer), &list); err == nil {
+		points := make([]Point, len(list))
+		for i, p := range list {
+			x, errX := strconv.Atoi(p.X.String())
+			y, errY := strconv.Atoi(p.Y.String())
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("captchaAIO: unexpected coordinates answer %s", answer)
+			}
+			points[i] = Point{X: x, Y: y}
+		}
+		return points, nil
+	}
+
+	answer = strings.TrimPrefix(answer, "coordinates:")
+	if answer == "" {
+		return nil, nil
+	}
+	var points []Point
+	for _, pair := range strings.Split(answer, ";") {
+		var p Point
+		if _, err := fmt.Sscanf(pair, "x=%d,y=%d", &p.X, &p.Y); err != nil {
+			return nil, fmt.Errorf("captchaAIO: unexpected coordinates answer %q", answer)
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// image starts a request for an image captcha, uploading file with
+// method=post, or sending b64 as the body with method=base64.
+func (*TwoCaptcha) image(file, b64 string) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{},
+		Files:  map[string]string{},
+	}
+	if b64 != "" {
+		req.Params["method"] = "base64"
+		req.Params["body"] = b64
+	} else {
+		req.Params["method"] = "post"
+		req.Files["file"] = file
+	}
+	return req
+}
+
+// grid builds a recaptcha=1 request for an image split into c.Rows by
+// c.Cols cells.
+func (tc *TwoCaptcha) grid(c Grid) TwoCaptchaRequest {
+	req := tc.image(c.File, c.Base64)
+	req.Params["recaptcha"] = "1"
+	if c.Rows != 0 {
+		req.Params["recaptcharows"] = strconv.Itoa(c.Rows)
+	}
+	if c.Cols != 0 {
+		req.Params["recaptchacols"] = strconv.Itoa(c.Cols)
+	}
+	if c.PreviousId != 0 {
+		req.Params["previousID"] = strconv.Itoa(c.PreviousId)
+	}
+	if c.CanSkip {
+		req.Params["can_no_answer"] = "1"
+	}
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	tc.instructions(&req, c.HintText, c.HintImageBase64, c.HintImageFile)
+	return req
+}
+
+// coordinates builds a coordinatescaptcha=1 request, answered with the
+// points the worker clicked.
+func (tc *TwoCaptcha) coordinates(c Coordinates) TwoCaptchaRequest {
+	req := tc.image(c.File, c.Base64)
+	req.Params["coordinatescaptcha"] = "1"
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	tc.instructions(&req, c.HintText, c.HintImageBase64, c.HintImageFile)
 	return req
-}
\ No newline at end of file
+}
+
+// rotate builds a method=rotatecaptcha request, uploading c.File, or each of
+// c.Files as file_1, file_2 and so on.
+func (tc *TwoCaptcha) rotate(c Rotate) TwoCaptchaRequest {
+	req := TwoCaptchaRequest{
+		Params: map[string]string{
+			"method": "rotatecaptcha",
+		},
+		Files: map[string]string{},
+	}
+	if c.File != "" {
+		req.Files["file"] = c.File
+	}
+	for i, file := range c.Files {
+		req.Files["file_"+strconv.Itoa(i+1)] = file
+	}
+	if c.Angle != 0 {
+		req.Params["angle"] = strconv.Itoa(c.Angle)
+	}
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	tc.instructions(&req, c.HintText, c.HintImageBase64, c.HintImageFile)
+	return req
+}
+
+// canvas builds a canvas=1 request, answered with the lines the worker drew.
+// 2Captcha rejects canvas captchas without a text or image hint, so they
+// fail with ErrBadParameters before being sent.
+func (tc *TwoCaptcha) canvas(c Canvas) (TwoCaptchaRequest, error) {
+	if c.HintText == "" && c.HintImageBase64 == "" && c.HintImageFile == "" {
+		return TwoCaptchaRequest{}, ErrBadParameters
+	}
+	req := tc.image(c.File, c.Base64)
+	req.Params["canvas"] = "1"
+	if c.PreviousId != 0 {
+		req.Params["previousID"] = strconv.Itoa(c.PreviousId)
+	}
+	if c.CanSkip {
+		req.Params["can_no_answer"] = "1"
+	}
+	if c.Lang != "" {
+		req.Params["lang"] = c.Lang
+	}
+	tc.instructions(&req, c.HintText, c.HintImageBase64, c.HintImageFile)
+	return req, nil
+}
+
+// instructions adds the text and image hints shown to the worker to req.
+func (*TwoCaptcha) instructions(req *TwoCaptchaRequest, text, imageBase64, imageFile string) {
+	if text != "" {
+		req.Params["textinstructions"] = text
+	}
+	if imageBase64 != "" {
+		req.Params["imginstructions"] = imageBase64
+	} else if imageFile != "" {
+		req.Files["imginstructions"] = imageFile
+	}
+}
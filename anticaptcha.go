@@ -0,0 +1,143 @@
+package captchaAIO
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+func NewAntiCaptchaClient(key string) *AntiCaptcha {
+	return &AntiCaptcha{
+		Key:          key,
+		BaseURL:      antiCaptchaURL,
+		FirstPoll:    time.Duration(5) * time.Second,
+		PollInterval: time.Duration(5) * time.Second,
+		http:         &http.Client{},
+	}
+}
+
+const antiCaptchaURL = "https://api.anti-captcha.com"
+
+// AntiCaptcha is a client for anti-captcha.com. Its API is the same
+// createTask/getTaskResult protocol CapMonster speaks.
+type AntiCaptcha struct {
+	Key string
+	// SoftID is the Anti-Captcha developer id credited for submitted tasks.
+	SoftID int
+	// BaseURL is the address of the API, https://api.anti-captcha.com unless
+	// pointed at a compatible service or a fake.
+	BaseURL string
+	// FirstPoll is how long Solve waits before polling a task for the first
+	// time and PollInterval how long it waits between polls.
+	FirstPoll    time.Duration
+	PollInterval time.Duration
+	http         *http.Client
+}
+
+func (ac *AntiCaptcha) logf(f string, v ...interface{}) {
+	if debug {
+		log.Printf(f, v...)
+	}
+}
+
+func (ac *AntiCaptcha) api() taskAPI {
+	return taskAPI{
+		http:     ac.http,
+		base:     ac.BaseURL,
+		provider: "anticaptcha",
+		mapError: taskError,
+	}
+}
+
+func (ac *AntiCaptcha) SetTimeout(t time.Duration) {
+	ac.http.Timeout = t
+}
+
+func (ac *AntiCaptcha) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return ac.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext creates the task and polls for its result until it is solved,
+// the service returns an error, or ctx is done.
+func (ac *AntiCaptcha) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	ac.logf("Starting AntiCaptcha")
+	start := time.Now()
+	id, err := ac.SendContext(ctx, captcha, proxy)
+	if err != nil {
+		return nil, err
+	}
+	ac.logf("Solving task: %v", id)
+	solution, err := poll(ctx, ac.FirstPoll, ac.PollInterval, func() (*Solution, error) {
+		return ac.GetResContext(ctx, id)
+	})
+	if err != nil {
+		ac.logf(err.Error())
+		return nil, err
+	}
+	solution.Duration = time.Since(start)
+	return solution, nil
+}
+
+// GetRes fetches the result of the task with the given id, returning
+// ErrCaptchaNotReady while a worker is still solving it.
+func (ac *AntiCaptcha) GetRes(id string) (*Solution, error) {
+	return ac.GetResContext(context.Background(), id)
+}
+
+// GetResContext is like GetRes, but aborts the request once ctx is done.
+func (ac *AntiCaptcha) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	return ac.api().getTaskResult(ctx, ac.Key, id)
+}
+
+// Send creates a task for the captcha on anti-captcha.com, will return the id
+// of the task or an error
+func (ac *AntiCaptcha) Send(captcha interface{}, proxy string) (string, error) {
+	return ac.SendContext(context.Background(), captcha, proxy)
+}
+
+// SendContext is like Send, but aborts the submission once ctx is done.
+func (ac *AntiCaptcha) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	var (
+		task map[string]interface{}
+		err  error
+	)
+	switch t := captcha.(type) {
+	case ReCaptcha:
+		task, err = reCaptchaTask(t, proxy)
+	case HCaptcha:
+		task, err = hCaptchaTask(t, proxy)
+	case FunCaptcha:
+		task, err = funCaptchaTask(t, proxy)
+	case GeeTest:
+		task, err = geeTestTask(t, proxy)
+	case Normal:
+		task, err = imageToTextTask(t)
+	default:
+		return "", ErrUnsupportedCaptcha
+	}
+	if err != nil {
+		return "", err
+	}
+
+	req := TaskRequest{
+		ClientKey: ac.Key,
+		Task:      task,
+		SoftID:    ac.SoftID,
+	}
+	ac.logf("%v", req.Task["type"])
+	res, err := ac.api().createTask(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	return res.id(), nil
+}
+
+func (ac *AntiCaptcha) GetBalance() (float64, error) {
+	return ac.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance, but aborts the request once ctx is done.
+func (ac *AntiCaptcha) GetBalanceContext(ctx context.Context) (float64, error) {
+	return ac.api().getBalance(ctx, ac.Key)
+}
@@ -1,29 +1,36 @@
 package captchaAIO
 
 import (
-	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
-	"net/url"
-	"strconv"
 	"time"
 )
 
 func NewCapMonsterClient(key string) *CapMonster {
 	return &CapMonster{
-		Key:  key,
-		http: &http.Client{},
+		Key:          key,
+		BaseURL:      capMonsterURL,
+		FirstPoll:    time.Duration(5) * time.Second,
+		PollInterval: time.Duration(5) * time.Second,
+		http:         &http.Client{},
 	}
 }
 
+const capMonsterURL = "https://api.capmonster.cloud"
+
 type CapMonster struct {
 	Key   string
 	Debug bool
-	http  *http.Client
+	// BaseURL is the address of the API, https://api.capmonster.cloud unless
+	// pointed at a compatible service or a fake.
+	BaseURL string
+	// FirstPoll is how long Solve waits before polling a task for the first
+	// time and PollInterval how long it waits between polls.
+	FirstPoll    time.Duration
+	PollInterval time.Duration
+	http         *http.Client
 }
 
 func (cm *CapMonster) logf(f string, v ...interface{}) {
@@ -32,284 +39,195 @@ func (cm *CapMonster) logf(f string, v ...interface{}) {
 	}
 }
 
+func (cm *CapMonster) api() taskAPI {
+	return taskAPI{
+		http:     cm.http,
+		base:     cm.BaseURL,
+		provider: "capmonster",
+		mapError: taskError,
+	}
+}
+
 func (cm *CapMonster) SetTimeout(t time.Duration) {
 	cm.http.Timeout = t
 }
 
-func (cm *CapMonster) Solve(captcha interface{}, proxy string) (string, error) {
+func (cm *CapMonster) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return cm.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext creates the task and polls for its result until it is solved,
+// the service returns an error, or ctx is done.
+func (cm *CapMonster) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
 	cm.logf("Starting CapMonster")
-	id, err := cm.Send(captcha, proxy)
+	start := time.Now()
+	id, err := cm.SendContext(ctx, captcha, proxy)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	cm.logf("Solving task: %v", id)
-	time.Sleep(time.Duration(5) * time.Second)
-	for {
-		solution, err := cm.GetRes(id)
-		if err != nil {
-			if errors.Is(err, ErrCaptchaNotReady) {
-				cm.logf("captcha not ready, waiting 5 seconds")
-				time.Sleep(time.Duration(5) * time.Second)
-				continue
-			}
-			cm.logf(err.Error())
-			return "", err
-		}
-		return solution, nil
-	}
-}
-func (cm *CapMonster) GetRes(id string) (string, error) {
-	payload := fmt.Sprintf(`{ "clientKey" : "%s", taskId: %s}`, cm.Key, id)
-	req, err := http.NewRequest("POST", "https://api.capmonster.cloud/getTaskResult", bytes.NewBuffer([]byte(payload)))
-	if err != nil {
-		return "", ErrNetwork
-	}
-	resp, err := cm.http.Do(req)
-	defer resp.Body.Close()
-	resBody, err := io.ReadAll(resp.Body)
+	solution, err := poll(ctx, cm.FirstPoll, cm.PollInterval, func() (*Solution, error) {
+		return cm.GetResContext(ctx, id)
+	})
 	if err != nil {
-		return "", err
+		cm.logf(err.Error())
+		return nil, err
 	}
-	data := string(resBody)
+	solution.Duration = time.Since(start)
+	return solution, nil
+}
 
-	type cmSolveRes struct {
-		Status   string `json:"status"`
-		Solution struct {
-			GRecaptchaResponse string `json:"gRecaptchaResponse"`
-		} `json:"solution"`
-	}
-	var cmRes cmSolveRes
-	err = json.Unmarshal([]byte(data), &cmRes)
-	if cmRes.Status != "ready" {
-		return "", ErrCaptchaNotReady
-	}
-	return cmRes.Solution.GRecaptchaResponse, nil
+// GetRes fetches the result of the task with the given id, returning
+// ErrCaptchaNotReady while a worker is still solving it.
+func (cm *CapMonster) GetRes(id string) (*Solution, error) {
+	return cm.GetResContext(context.Background(), id)
+}
 
+// GetResContext is like GetRes, but aborts the request once ctx is done.
+func (cm *CapMonster) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	return cm.api().getTaskResult(ctx, cm.Key, id)
 }
 
 func (cm *CapMonster) Send(captcha interface{}, proxy string) (string, error) {
-	var cmReq CapMonsterRequest
+	return cm.SendContext(context.Background(), captcha, proxy)
+}
 
+// SendContext is like Send, but aborts the submission once ctx is done.
+func (cm *CapMonster) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	var (
+		task map[string]interface{}
+		err  error
+	)
 	switch t := captcha.(type) {
 	case ReCaptcha:
-		cmReq = cm.reCaptcha(t)
-		if t.Version == "3" {
-			cmReq.Task["task"] = "RecaptchaV3TaskProxyless"
-		}
-		if t.Version == "2" && proxy != "" {
-			cmReq.Task["task"] = "NoCaptchaTask"
-		} else {
-			if t.Version != "2" && t.Version != "3" {
-				return "", ErrUnsupportedCaptcha
-			}
-			cmReq.Task["task"] = "NoCaptchaTaskProxyless"
-
-		}
+		task, err = reCaptchaTask(t, proxy)
 	case HCaptcha:
-		cmReq = cm.hCaptcha(t)
-		if proxy != "" {
-			cmReq.Task["task"] = "HCaptchaTaskProxyless"
-		} else {
-			cmReq.Task["task"] = "HCaptchaTask"
-		}
+		task, err = cm.hCaptcha(t, proxy)
+	case Normal:
+		task, err = cm.imageToText(t)
+	case FunCaptcha:
+		task, err = funCaptchaTask(t, proxy)
+	case GeeTest:
+		task, err = geeTestTask(t, proxy)
+	case GeeTestV4:
+		task, err = geeTestV4Task(t, proxy)
+	case Turnstile:
+		task, err = cm.turnstile(t, proxy)
+	case Grid:
+		task, err = cm.grid(t)
 	default:
 		return "", ErrUnsupportedCaptcha
 	}
-
-	cmReq.ClientKey = cm.Key
-	if proxy != "" {
-		u, err := url.Parse(proxy)
-		if err != nil {
-			return "", err
-		}
-		cmReq.Task["proxyType"] = u.Scheme
-		cmReq.Task["proxyAddress"] = u.Hostname()
-		cmReq.Task["proxyPort"] = u.Port()
-		cmReq.Task["proxyLogin"] = u.User.Username()
-		if pass, ok := u.User.Password(); ok {
-			cmReq.Task["proxyPassword"] = pass
-		}
-	}
-
-	body, _ := formatJSON(&cmReq)
-
-	req, err := http.NewRequest("POST", "https://api.capmonster.cloud/createTask", bytes.NewBuffer([]byte(body)))
 	if err != nil {
-
-		return "", ErrNetwork
-	}
-	//	req.Header.Set("content-type", "application/json")
-	resp, err := cm.http.Do(req)
-	if err != nil {
-
 		return "", err
 	}
-	defer resp.Body.Close()
-	resBody, err := io.ReadAll(resp.Body)
-	if err != nil {
 
-		return "", err
+	req := TaskRequest{
+		ClientKey: cm.Key,
+		Task:      task,
 	}
-	data := string(resBody)
-
-	type cmCreateTaskRes struct {
-		TaskId    int    `json:"taskId"`
-		ErrorCode string `json:"errorCode"`
-	}
-
-	var cmRes cmCreateTaskRes
-	err = json.Unmarshal([]byte(data), &cmRes)
+	cm.logf("%v", req.Task["type"])
+	res, err := cm.api().createTask(ctx, req)
 	if err != nil {
 		return "", err
 	}
-
-	if cmRes.ErrorCode != "" {
-		switch cmRes.ErrorCode {
-		case "CAPCHA_NOT_READY":
-			return "", ErrCaptchaNotReady
-		case "ERROR_KEY_DOES_NOT_EXIST":
-			return "", ErrKeyDoesNotExist
-		case "ERROR_WRONG_IP_NOT_ALLOWED":
-			return "", ErrIPNotAllowed
-		case "ERROR_TOO_BIG_CAPTCHA_FILESIZE":
-			return "", ErrTooBigCaptcha
-		case "ERROR_ZERO_BALANCE":
-			return "", ErrZeroBalance
-		case "ERROR_CAPTCHA_UNSOLVABLE":
-			return "", ErrCaptchaUnsolvable
-		case "ERROR_NO_SUCH_CAPCHA_ID":
-			return "", ErrNoSuchCaptchaID
-		case "WRONG_CAPTCHA_ID":
-			return "", ErrWrongCaptchaID
-		case "ERROR_IP_BANNED":
-			return "", ErrIPBanned
-		case "ERROR_NO_SUCH_METHOD":
-			return "", ErrNoSuchMethod
-		case "ERROR_TOO_MANY_REQUESTS":
-			return "", ErrTooManyRequests
-		}
-	}
-
-	return strconv.Itoa(cmRes.TaskId), nil
-
+	return res.id(), nil
 }
-func formatJSON(cmr *CapMonsterRequest) (string, error) {
-
-	formattedJSON := fmt.Sprintf(`{
-					"clientKey": "%s",
-                    "task": {
-						"type": "%s",
-						"websiteURL": "%s",
-       					"websiteKey": "%s",
-                        "proxyType": "%s",
-						"proxyAddress": "%s",
-						"proxyPort": "%s",
-						"proxyLogin": "%s",
-						"proxyPassword": "%s",
-						"userAgent": "%s",
-						"minScore": "%s",
-						"pageAction": "%s"
-
-					 }
- 
-
-
-                   }`, cmr.ClientKey, cmr.Task["task"], cmr.Task["websiteURL"], cmr.Task["websiteKey"], cmr.Task["proxyType"], cmr.Task["proxyAddress"],
 
-		cmr.Task["proxyPort"], cmr.Task["proxyLogin"], cmr.Task["proxyPassword"], cmr.Task["userAgent"], cmr.Task["minScore"], cmr.Task["pageAction"])
-	return formattedJSON, nil
+func (cm *CapMonster) GetBalance() (float64, error) {
+	return cm.GetBalanceContext(context.Background())
 }
 
-type CapMonsterRequest struct {
-	ClientKey string            `json:"clientkey"`
-	Task      map[string]string `json:"task"`
+// GetBalanceContext is like GetBalance, but aborts the request once ctx is done.
+func (cm *CapMonster) GetBalanceContext(ctx context.Context) (float64, error) {
+	return cm.api().getBalance(ctx, cm.Key)
 }
 
-func (cm *CapMonster) reCaptcha(c ReCaptcha) CapMonsterRequest {
-	req := CapMonsterRequest{}
-	req.Task = make(map[string]string)
-
-	if c.PageUrl != "" {
-		req.Task["websiteURL"] = c.PageUrl
+// hCaptcha builds an HCaptchaTask, passing rqdata as data and cookies as a
+// single string the way CapMonster expects them.
+func (cm *CapMonster) hCaptcha(c HCaptcha, proxy string) (map[string]interface{}, error) {
+	task, err := hCaptchaTask(c, proxy)
+	if err != nil {
+		return nil, err
 	}
-
-	if c.SiteKey != "" {
-		req.Task["websiteKey"] = c.SiteKey
+	delete(task, "enterprisePayload")
+	if c.RqData != "" {
+		task["data"] = c.RqData
 	}
+	if len(c.Cookies) > 0 {
+		task["cookies"] = cookieString(c.Cookies)
+	}
+	return task, nil
+}
 
-	if c.Score != 0 {
-		req.Task["minScore"] = strconv.FormatFloat(c.Score, 'f', -1, 64)
+// turnstile builds a TurnstileTask, solving the challenge page variant with
+// a cloudflareTaskType of token when c.PageData is set.
+func (cm *CapMonster) turnstile(c Turnstile, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"type":       "TurnstileTask",
+		"websiteURL": c.PageUrl,
+		"websiteKey": c.SiteKey,
+	}
+	if c.PageData != "" {
+		task["cloudflareTaskType"] = "token"
+		task["pageData"] = c.PageData
 	}
 	if c.Action != "" {
-		req.Task["pageAction"] = c.Action
+		task["pageAction"] = c.Action
 	}
-	if c.UserAgent != "" {
-		req.Task["userAgent"] = c.UserAgent
+	if c.CData != "" {
+		task["data"] = c.CData
 	}
-	return req
-}
-
-func (cm *CapMonster) hCaptcha(c HCaptcha) CapMonsterRequest {
-
-	req := CapMonsterRequest{}
-	req.Task = make(map[string]string)
-	req.Task["websiteURL"] = c.PageUrl
-	req.Task["websiteKey"] = c.SiteKey
 	if c.UserAgent != "" {
-		req.Task["userAgent"] = c.UserAgent
+		task["userAgent"] = c.UserAgent
 	}
-
-	return req
+	return task, setTaskProxy(task, proxy)
 }
-func (cm *CapMonster) GetBalance() (float64, error) {
-	payload := fmt.Sprintf(`{ "clientKey": "%s"  }`, cm.Key)
-	req, err := http.NewRequest("POST", "https://api.capmonster.cloud/getBalance", bytes.NewBuffer([]byte(payload)))
+
+// grid builds a ComplexImageTask of class recaptcha for c, defaulting to a
+// 3x3 grid.
+func (cm *CapMonster) grid(c Grid) (map[string]interface{}, error) {
+	body, err := imageBase64(c.File, c.Base64)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	resp, err := cm.http.Do(req)
-	if err != nil {
-		return 0, ErrNetwork
+	rows, cols := c.Rows, c.Cols
+	if rows == 0 {
+		rows = 3
 	}
-	defer resp.Body.Close()
-	resBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-
-		return 0, err
+	if cols == 0 {
+		cols = 3
+	}
+	metadata := map[string]string{
+		"Grid": fmt.Sprintf("%dx%d", rows, cols),
 	}
-	data := string(resBody)
-	type cmGetBalanceRes struct {
-		ErrorCode string  `json:"errorCode"`
-		Balance   float64 `json:"balance"`
+	if c.HintText != "" {
+		metadata["Task"] = c.HintText
 	}
-	var cmBalance cmGetBalanceRes
+	return map[string]interface{}{
+		"type":         "ComplexImageTask",
+		"class":        "recaptcha",
+		"imagesBase64": []string{body},
+		"metadata":     metadata,
+	}, nil
+}
 
-	err = json.Unmarshal([]byte(data), &cmBalance)
-	if cmBalance.ErrorCode != "" {
-		switch cmBalance.ErrorCode {
-		case "CAPCHA_NOT_READY":
-			return 0, ErrCaptchaNotReady
-		case "ERROR_KEY_DOES_NOT_EXIST":
-			return 0, ErrKeyDoesNotExist
-		case "ERROR_WRONG_IP_NOT_ALLOWED":
-			return 0, ErrIPNotAllowed
-		case "ERROR_TOO_BIG_CAPTCHA_FILESIZE":
-			return 0, ErrTooBigCaptcha
-		case "ERROR_ZERO_BALANCE":
-			return 0, ErrZeroBalance
-		case "ERROR_CAPTCHA_UNSOLVABLE":
-			return 0, ErrCaptchaUnsolvable
-		case "ERROR_NO_SUCH_CAPCHA_ID":
-			return 0, ErrNoSuchCaptchaID
-		case "WRONG_CAPTCHA_ID":
-			return 0, ErrWrongCaptchaID
-		case "ERROR_IP_BANNED":
-			return 0, ErrIPBanned
-		case "ERROR_NO_SUCH_METHOD":
-			return 0, ErrNoSuchMethod
-		case "ERROR_TOO_MANY_REQUESTS":
-			return 0, ErrTooManyRequests
-		}
+// imageToText builds an ImageToTextTask with CapMonster's module and
+// threshold options.
+func (cm *CapMonster) imageToText(c Normal) (map[string]interface{}, error) {
+	task, err := imageToTextTask(c)
+	if err != nil {
+		return nil, err
+	}
+	// CapMonster can only restrict answers to digits
+	if task["numeric"] != 1 {
+		delete(task, "numeric")
+	}
+	if c.Module != "" {
+		task["CapMonsterModule"] = c.Module
+	}
+	if c.RecognizingThreshold != 0 {
+		task["recognizingThreshold"] = c.RecognizingThreshold
 	}
-	return cmBalance.Balance, nil
+	return task, nil
 }
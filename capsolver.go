@@ -0,0 +1,261 @@
+package captchaAIO
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+func NewCapSolverClient(key string) *CapSolver {
+	return &CapSolver{
+		Key:          key,
+		BaseURL:      capSolverURL,
+		FirstPoll:    time.Duration(3) * time.Second,
+		PollInterval: time.Duration(3) * time.Second,
+		http:         &http.Client{},
+	}
+}
+
+const capSolverURL = "https://api.capsolver.com"
+
+// CapSolver is a client for capsolver.com, which speaks a variant of the
+// Anti-Captcha JSON protocol with its own task names and error codes.
+type CapSolver struct {
+	Key string
+	// AppID is the CapSolver developer id credited for submitted tasks.
+	AppID string
+	// BaseURL is the address of the API, https://api.capsolver.com unless
+	// pointed at a compatible service or a fake.
+	BaseURL string
+	// FirstPoll is how long Solve waits before polling a task for the first
+	// time and PollInterval how long it waits between polls.
+	FirstPoll    time.Duration
+	PollInterval time.Duration
+	http         *http.Client
+
+	mu sync.Mutex
+	// solved holds the solutions of tasks createTask answered straight away,
+	// which getTaskResult does not know about, until GetRes returns them.
+	solved map[string]*Solution
+}
+
+func (cs *CapSolver) logf(f string, v ...interface{}) {
+	if debug {
+		log.Printf(f, v...)
+	}
+}
+
+func (cs *CapSolver) api() taskAPI {
+	return taskAPI{
+		http:     cs.http,
+		base:     cs.BaseURL,
+		provider: "capsolver",
+		mapError: capSolverError,
+	}
+}
+
+func (cs *CapSolver) SetTimeout(t time.Duration) {
+	cs.http.Timeout = t
+}
+
+func (cs *CapSolver) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return cs.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext creates the task and polls for its result until it is solved,
+// the service returns an error, or ctx is done. Image tasks are solved
+// synchronously by CapSolver and return without polling.
+func (cs *CapSolver) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	cs.logf("Starting CapSolver")
+	start := time.Now()
+	res, err := cs.send(ctx, captcha, proxy)
+	if err != nil {
+		return nil, err
+	}
+	id := res.id()
+	if res.Status == "ready" {
+		solution := res.solution(id, "capsolver")
+		solution.Duration = time.Since(start)
+		return solution, nil
+	}
+	cs.logf("Solving task: %v", id)
+	solution, err := poll(ctx, cs.FirstPoll, cs.PollInterval, func() (*Solution, error) {
+		return cs.GetResContext(ctx, id)
+	})
+	if err != nil {
+		cs.logf(err.Error())
+		return nil, err
+	}
+	solution.Duration = time.Since(start)
+	return solution, nil
+}
+
+// GetRes fetches the result of the task with the given id, returning
+// ErrCaptchaNotReady while a worker is still solving it.
+func (cs *CapSolver) GetRes(id string) (*Solution, error) {
+	return cs.GetResContext(context.Background(), id)
+}
+
+// GetResContext is like GetRes, but aborts the request once ctx is done.
+func (cs *CapSolver) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	cs.mu.Lock()
+	solution, ok := cs.solved[id]
+	delete(cs.solved, id)
+	cs.mu.Unlock()
+	if ok {
+		return solution, nil
+	}
+	return cs.api().getTaskResult(ctx, cs.Key, id)
+}
+
+// Send creates a task for the captcha on capsolver.com, will return the id
+// of the task or an error
+func (cs *CapSolver) Send(captcha interface{}, proxy string) (string, error) {
+	return cs.SendContext(context.Background(), captcha, proxy)
+}
+
+// SendContext is like Send, but aborts the submission once ctx is done. The
+// solution of a task CapSolver solved straight away is kept for GetRes.
+func (cs *CapSolver) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	res, err := cs.send(ctx, captcha, proxy)
+	if err != nil {
+		return "", err
+	}
+	id := res.id()
+	if res.Status == "ready" {
+		cs.mu.Lock()
+		if cs.solved == nil {
+			cs.solved = map[string]*Solution{}
+		}
+		cs.solved[id] = res.solution(id, "capsolver")
+		cs.mu.Unlock()
+	}
+	return id, nil
+}
+
+func (cs *CapSolver) send(ctx context.Context, captcha interface{}, proxy string) (*taskResponse, error) {
+	var (
+		task map[string]interface{}
+		err  error
+	)
+	switch t := captcha.(type) {
+	case ReCaptcha:
+		task, err = cs.reCaptcha(t, proxy)
+	case HCaptcha:
+		task, err = hCaptchaTask(t, proxy)
+	case FunCaptcha:
+		task, err = funCaptchaTask(t, proxy)
+	case GeeTest:
+		task, err = geeTestTask(t, proxy)
+	case Turnstile:
+		task = cs.turnstile(t)
+	case Normal:
+		task, err = imageToTextTask(t)
+	default:
+		return nil, ErrUnsupportedCaptcha
+	}
+	if err != nil {
+		return nil, err
+	}
+	capSolverTaskType(task)
+
+	req := TaskRequest{
+		ClientKey: cs.Key,
+		Task:      task,
+		AppID:     cs.AppID,
+	}
+	cs.logf("%v", req.Task["type"])
+	return cs.api().createTask(ctx, req)
+}
+
+func (cs *CapSolver) GetBalance() (float64, error) {
+	return cs.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance, but aborts the request once ctx is done.
+func (cs *CapSolver) GetBalanceContext(ctx context.Context) (float64, error) {
+	return cs.api().getBalance(ctx, cs.Key)
+}
+
+// reCaptcha builds the shared reCAPTCHA task, except that CapSolver solves V3
+// through a proxy as a ReCaptchaV3Task when one is given.
+func (*CapSolver) reCaptcha(c ReCaptcha, proxy string) (map[string]interface{}, error) {
+	if c.Version != "3" || proxy == "" {
+		return reCaptchaTask(c, proxy)
+	}
+	task, err := reCaptchaTask(c, "")
+	if err != nil {
+		return nil, err
+	}
+	task["type"] = "RecaptchaV3Task"
+	return task, setTaskProxy(task, proxy)
+}
+
+// turnstile builds an AntiTurnstileTaskProxyLess, CapSolver does not solve
+// Turnstile through a proxy.
+func (*CapSolver) turnstile(c Turnstile) map[string]interface{} {
+	task := map[string]interface{}{
+		"type":       "AntiTurnstileTaskProxyLess",
+		"websiteURL": c.PageUrl,
+		"websiteKey": c.SiteKey,
+	}
+	metadata := map[string]string{}
+	if c.Action != "" {
+		metadata["action"] = c.Action
+	}
+	if c.CData != "" {
+		metadata["cdata"] = c.CData
+	}
+	if len(metadata) > 0 {
+		task["metadata"] = metadata
+	}
+	return task
+}
+
+// capSolverTaskType renames the type of a task built for the Anti-Captcha
+// protocol to CapSolver's spelling, e.g. RecaptchaV2TaskProxyless becomes
+// ReCaptchaV2TaskProxyLess.
+func capSolverTaskType(task map[string]interface{}) {
+	t := task["type"].(string)
+	t = strings.Replace(t, "Recaptcha", "ReCaptcha", 1)
+	t = strings.Replace(t, "Proxyless", "ProxyLess", 1)
+	if strings.HasPrefix(t, "ReCaptchaV3Task") && task["isEnterprise"] == true {
+		t = strings.Replace(t, "V3Task", "V3EnterpriseTask", 1)
+		delete(task, "isEnterprise")
+	}
+	task["type"] = t
+}
+
+// capSolverError maps an errorCode returned by CapSolver to one of the
+// package's errors, falling back to the codes it shares with Anti-Captcha.
+func capSolverError(code string) error {
+	switch code {
+	case "ERROR_KEY_DENIED_ACCESS":
+		return ErrKeyDoesNotExist
+	case "ERROR_ZERO_BALANCE", "ERROR_SETTLEMENT_FAILED":
+		return ErrZeroBalance
+	case "ERROR_SERVICE_UNAVALIABLE":
+		return ErrNoSlotAvailable
+	case "ERROR_RATE_LIMIT":
+		return ErrTooManyRequests
+	case "ERROR_INVALID_TASK_DATA", "ERROR_BAD_REQUEST":
+		return ErrBadParameters
+	case "ERROR_TASKID_INVALID":
+		return ErrWrongCaptchaID
+	case "ERROR_TASK_TIMEOUT", "ERROR_CAPTCHA_UNSOLVABLE", "ERROR_UNKNOWN_QUESTION":
+		return ErrCaptchaUnsolvable
+	case "ERROR_TASK_NOT_SUPPORTED":
+		return ErrUnsupportedCaptcha
+	case "ERROR_PROXY_BANNED":
+		return ErrProxyConnFail
+	case "ERROR_INVALID_IMAGE", "ERROR_PARSE_IMAGE_FAIL":
+		return ErrImageTypeNotSupported
+	case "ERROR_IP_BANNED", "ERROR_KEY_TEMP_BLOCKED":
+		return ErrIPBanned
+	default:
+		return taskError(code)
+	}
+}
@@ -0,0 +1,159 @@
+package captchatest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// CapMonster is a fake of the CapMonster createTask, getTaskResult and
+// getBalance API. Anti-Captcha speaks the same protocol, so it can stand in
+// for that service too.
+type CapMonster struct {
+	*httptest.Server
+	scripts
+	// Key, if set, is the only client key accepted.
+	Key string
+	// Balance is returned by getBalance.
+	Balance float64
+
+	mu    sync.Mutex
+	tasks []map[string]interface{}
+}
+
+// NewCapMonster starts a fake CapMonster server. Close it when done.
+func NewCapMonster() *CapMonster {
+	f := &CapMonster{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/createTask", f.createTask)
+	mux.HandleFunc("/getTaskResult", f.getTaskResult)
+	mux.HandleFunc("/getBalance", f.getBalance)
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// Tasks returns the task objects submitted to createTask so far.
+func (f *CapMonster) Tasks() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.tasks...)
+}
+
+type capMonsterRequest struct {
+	ClientKey string                 `json:"clientKey"`
+	Task      map[string]interface{} `json:"task"`
+	TaskID    json.Number            `json:"taskId"`
+}
+
+// decode reads the request body, writing an error response and returning
+// false if it is malformed or uses the wrong key.
+func (f *CapMonster) decode(w http.ResponseWriter, r *http.Request) (capMonsterRequest, bool) {
+	var req capMonsterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCapMonsterError(w, "ERROR_NO_SUCH_METHOD")
+		return req, false
+	}
+	if f.Key != "" && req.ClientKey != f.Key {
+		writeCapMonsterError(w, "ERROR_KEY_DOES_NOT_EXIST")
+		return req, false
+	}
+	return req, true
+}
+
+func (f *CapMonster) createTask(w http.ResponseWriter, r *http.Request) {
+	req, ok := f.decode(w, r)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	f.tasks = append(f.tasks, req.Task)
+	f.mu.Unlock()
+
+	sc := f.next()
+	time.Sleep(sc.Delay)
+	if sc.SubmitError != "" {
+		writeCapMonsterError(w, sc.SubmitError)
+		return
+	}
+	if sc.Sync {
+		res := sc.result()
+		res["taskId"] = f.newID()
+		writeCapMonster(w, res)
+		return
+	}
+	id := f.add(sc)
+	writeCapMonster(w, map[string]interface{}{
+		"errorId": 0,
+		"taskId":  id,
+	})
+}
+
+func (f *CapMonster) getTaskResult(w http.ResponseWriter, r *http.Request) {
+	req, ok := f.decode(w, r)
+	if !ok {
+		return
+	}
+	id := req.TaskID.String()
+	time.Sleep(f.delay(id))
+	sc, ready, ok := f.poll(id)
+	switch {
+	case !ok:
+		writeCapMonsterError(w, "ERROR_NO_SUCH_CAPCHA_ID")
+	case !ready:
+		writeCapMonster(w, map[string]interface{}{
+			"errorId": 0,
+			"status":  "processing",
+		})
+	case sc.ResultError != "":
+		writeCapMonsterError(w, sc.ResultError)
+	default:
+		writeCapMonster(w, sc.result())
+	}
+}
+
+// result returns the getTaskResult response of a solved task following sc.
+func (sc Script) result() map[string]interface{} {
+	solution := map[string]interface{}{}
+	if token, ok := sc.answer().(string); ok {
+		solution["gRecaptchaResponse"] = token
+	} else {
+		data, _ := json.Marshal(sc.answer())
+		json.Unmarshal(data, &solution)
+	}
+	if sc.UserAgent != "" {
+		solution["userAgent"] = sc.UserAgent
+	}
+	if sc.RespKey != "" {
+		solution["respKey"] = sc.RespKey
+	}
+	return map[string]interface{}{
+		"errorId":  0,
+		"status":   "ready",
+		"solution": solution,
+		"cost":     sc.Cost,
+	}
+}
+
+func (f *CapMonster) getBalance(w http.ResponseWriter, r *http.Request) {
+	if _, ok := f.decode(w, r); !ok {
+		return
+	}
+	writeCapMonster(w, map[string]interface{}{
+		"errorId": 0,
+		"balance": f.Balance,
+	})
+}
+
+func writeCapMonsterError(w http.ResponseWriter, code string) {
+	writeCapMonster(w, map[string]interface{}{
+		"errorId":   1,
+		"errorCode": code,
+	})
+}
+
+func writeCapMonster(w http.ResponseWriter, body map[string]interface{}) {
+	w.Header().Set("content-type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
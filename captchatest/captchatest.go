@@ -0,0 +1,136 @@
+// Package captchatest provides in-process fakes of the 2Captcha and
+// CapMonster APIs for testing code built on captchaAIO without network
+// access or a funded account.
+//
+// Point a client at a fake by setting its BaseURL:
+//
+//	srv := captchatest.NewTwoCaptcha()
+//	defer srv.Close()
+//	client := captchaAIO.NewTwoCaptchaClient("key")
+//	client.BaseURL = srv.URL
+//
+// Each submitted task follows a Script, which decides how long the fake
+// takes to answer, how often the task is reported as not ready and which
+// answer or error code it ends with.
+package captchatest
+
+import (
+	"sync"
+	"time"
+)
+
+// Script describes how a fake handles a submitted task.
+type Script struct {
+	// SubmitError is returned instead of a task id when set, e.g.
+	// "ERROR_ZERO_BALANCE".
+	SubmitError string
+	// NotReady is how many polls report the task as not ready before the
+	// result is returned.
+	NotReady int
+	// ResultError is returned instead of the answer once the task is ready,
+	// e.g. "ERROR_CAPTCHA_UNSOLVABLE".
+	ResultError string
+	// Delay is waited before answering each request for the task.
+	Delay time.Duration
+	// Answer is the solution. A string is returned as the token, any other
+	// value is JSON encoded: as the request of a 2Captcha response, or as
+	// the solution object of a CapMonster response.
+	Answer interface{}
+	// UserAgent and RespKey are returned alongside the answer when set.
+	UserAgent string
+	RespKey   string
+	// Cost is the price reported for the task.
+	Cost float64
+	// Sync makes createTask answer with the result straight away, the way
+	// CapSolver solves image tasks. NotReady and ResultError are ignored, and
+	// like CapSolver the task is unknown to getTaskResult.
+	Sync bool
+}
+
+// DefaultAnswer is the token returned for tasks whose Script has no Answer.
+const DefaultAnswer = "03AGdBq24PBCbwiDRaS_MJ7Z"
+
+// scripts is the queue of Scripts shared by the fakes.
+type scripts struct {
+	mu sync.Mutex
+	// Default is used for tasks submitted once the queue is empty.
+	Default Script
+	queue   []Script
+	tasks   map[string]*task
+	nextID  int
+}
+
+type task struct {
+	script Script
+	polls  int
+}
+
+// Push queues scripts to be used, in order, for the next submitted tasks.
+func (s *scripts) Push(scripts ...Script) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, scripts...)
+}
+
+// next pops the Script for a newly submitted task.
+func (s *scripts) next() Script {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return s.Default
+	}
+	sc := s.queue[0]
+	s.queue = s.queue[1:]
+	return sc
+}
+
+// add registers a task following sc and returns its id.
+func (s *scripts) add(sc Script) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tasks == nil {
+		s.tasks = map[string]*task{}
+	}
+	s.nextID++
+	s.tasks[idString(s.nextID)] = &task{script: sc}
+	return s.nextID
+}
+
+// newID returns an id for a task that is answered straight away and so never
+// registered.
+func (s *scripts) newID() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return s.nextID
+}
+
+// poll records a poll of task id, reporting whether it is ready. ok is false
+// if no such task exists.
+func (s *scripts) poll(id string) (sc Script, ready, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.tasks[id]
+	if !ok {
+		return Script{}, false, false
+	}
+	t.polls++
+	return t.script, t.polls > t.script.NotReady, true
+}
+
+// delay returns the Delay of task id.
+func (s *scripts) delay(id string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t, ok := s.tasks[id]; ok {
+		return t.script.Delay
+	}
+	return 0
+}
+
+func (sc Script) answer() interface{} {
+	if sc.Answer == nil {
+		return DefaultAnswer
+	}
+	return sc.Answer
+}
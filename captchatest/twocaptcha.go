@@ -0,0 +1,184 @@
+package captchatest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// TwoCaptcha is a fake of the 2Captcha in.php and res.php API.
+type TwoCaptcha struct {
+	*httptest.Server
+	scripts
+	// Key, if set, is the only API key accepted.
+	Key string
+	// Balance is returned by action=getbalance.
+	Balance float64
+
+	mu          sync.Mutex
+	submissions []Submission
+	reports     map[string]bool
+}
+
+// Submission is a task submitted to in.php.
+type Submission struct {
+	Params url.Values
+	// Files holds the contents of uploaded files by form field name.
+	Files map[string][]byte
+}
+
+// NewTwoCaptcha starts a fake 2Captcha server. Close it when done.
+func NewTwoCaptcha() *TwoCaptcha {
+	f := &TwoCaptcha{
+		reports: map[string]bool{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/in.php", f.in)
+	mux.HandleFunc("/res.php", f.res)
+	f.Server = httptest.NewServer(mux)
+	return f
+}
+
+// Submissions returns the tasks submitted so far.
+func (f *TwoCaptcha) Submissions() []Submission {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Submission(nil), f.submissions...)
+}
+
+// Reports returns the reports received so far, keyed by task id with
+// whether the task was reported as correct.
+func (f *TwoCaptcha) Reports() map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	reports := map[string]bool{}
+	for id, correct := range f.reports {
+		reports[id] = correct
+	}
+	return reports
+}
+
+func (f *TwoCaptcha) in(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	sub := Submission{Params: r.Form, Files: map[string][]byte{}}
+	if r.MultipartForm != nil {
+		for name, headers := range r.MultipartForm.File {
+			file, err := headers[0].Open()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			sub.Files[name], _ = io.ReadAll(file)
+			file.Close()
+		}
+	}
+	f.mu.Lock()
+	f.submissions = append(f.submissions, sub)
+	f.mu.Unlock()
+
+	asJSON := r.Form.Get("json") == "1"
+	if f.Key != "" && r.Form.Get("key") != f.Key {
+		writeTwoCaptcha(w, asJSON, 0, "ERROR_KEY_DOES_NOT_EXIST", nil)
+		return
+	}
+	sc := f.next()
+	time.Sleep(sc.Delay)
+	if sc.SubmitError != "" {
+		writeTwoCaptcha(w, asJSON, 0, sc.SubmitError, nil)
+		return
+	}
+	id := f.add(sc)
+	writeTwoCaptcha(w, asJSON, 1, idString(id), nil)
+}
+
+func (f *TwoCaptcha) res(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	asJSON := q.Get("json") == "1"
+	if f.Key != "" && q.Get("key") != f.Key {
+		writeTwoCaptcha(w, asJSON, 0, "ERROR_KEY_DOES_NOT_EXIST", nil)
+		return
+	}
+	id := q.Get("id")
+	switch q.Get("action") {
+	case "getbalance":
+		balance := strconv.FormatFloat(f.Balance, 'f', -1, 64)
+		if asJSON {
+			writeTwoCaptcha(w, asJSON, 1, balance, nil)
+		} else {
+			fmt.Fprint(w, balance)
+		}
+	case "reportgood", "reportbad":
+		f.mu.Lock()
+		f.reports[id] = q.Get("action") == "reportgood"
+		f.mu.Unlock()
+		if asJSON {
+			writeTwoCaptcha(w, asJSON, 1, "OK_REPORT_RECORDED", nil)
+		} else {
+			fmt.Fprint(w, "OK_REPORT_RECORDED")
+		}
+	case "get", "get2":
+		time.Sleep(f.delay(id))
+		sc, ready, ok := f.poll(id)
+		switch {
+		case !ok:
+			writeTwoCaptcha(w, asJSON, 0, "ERROR_WRONG_CAPTCHA_ID", nil)
+		case !ready:
+			writeTwoCaptcha(w, asJSON, 0, "CAPCHA_NOT_READY", nil)
+		case sc.ResultError != "":
+			writeTwoCaptcha(w, asJSON, 0, sc.ResultError, nil)
+		default:
+			extra := map[string]interface{}{}
+			if sc.UserAgent != "" {
+				extra["useragent"] = sc.UserAgent
+			}
+			if sc.RespKey != "" {
+				extra["respKey"] = sc.RespKey
+			}
+			if q.Get("action") == "get2" {
+				extra["price"] = strconv.FormatFloat(sc.Cost, 'f', -1, 64)
+			}
+			writeTwoCaptcha(w, asJSON, 1, sc.answer(), extra)
+		}
+	default:
+		writeTwoCaptcha(w, asJSON, 0, "ERROR_EMPTY_ACTION", nil)
+	}
+}
+
+// writeTwoCaptcha writes a response in the JSON format used when json=1 is
+// set, or the plain text format otherwise.
+func writeTwoCaptcha(w http.ResponseWriter, asJSON bool, status int, request interface{}, extra map[string]interface{}) {
+	if asJSON {
+		body := map[string]interface{}{
+			"status":  status,
+			"request": request,
+		}
+		for k, v := range extra {
+			body[k] = v
+		}
+		w.Header().Set("content-type", "application/json")
+		json.NewEncoder(w).Encode(body)
+		return
+	}
+	text, ok := request.(string)
+	if !ok {
+		data, _ := json.Marshal(request)
+		text = string(data)
+	}
+	if status == 1 {
+		text = "OK|" + text
+	}
+	fmt.Fprint(w, text)
+}
+
+func idString(id int) string {
+	return strconv.Itoa(id)
+}
@@ -1,8 +1,15 @@
 package captchaAIO
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 )
 
 var debug bool
@@ -56,6 +63,17 @@ type (
 		ApiServer string
 	}
 
+	// GeeTestV4 is a GeeTest v4 widget, configured by its captcha_id rather
+	// than the gt and challenge pair of v3.
+	GeeTestV4 struct {
+		CaptchaID string
+		Url       string
+		ApiServer string
+		RiskType  string
+		// Extra holds any other init parameters the widget is created with.
+		Extra map[string]string
+	}
+
 	Grid struct {
 		File            string
 		Base64          string
@@ -70,11 +88,22 @@ type (
 	}
 
 	HCaptcha struct {
-		SiteKey string
-		PageUrl     string
+		SiteKey   string
+		PageUrl   string
 		UserAgent string
+		// Invisible marks the widget as invisible.
+		Invisible bool
+		// RqData is the rqdata value an Enterprise widget is rendered with.
+		RqData string
+		// Cookies are sent to the solver for sites that check them.
+		Cookies map[string]string
+		// ApiDomain is the domain the widget is loaded from when it is not
+		// hcaptcha.com, e.g. js.hcaptcha.com.
+		ApiDomain string
 	}
 
+	// KeyCaptcha holds the s_s_c_* values of a KeyCaptcha widget. The
+	// solution's Token is the value of its capcode field.
 	KeyCaptcha struct {
 		UserId         int
 		SessionId      string
@@ -84,8 +113,12 @@ type (
 	}
 
 	Normal struct {
-		File            string
-		Base64          string
+		File   string
+		Base64 string
+		// Image and Reader supply the image from memory instead of File. Race
+		// and Failover read Reader once and send every provider the same image.
+		Image           []byte
+		Reader          io.Reader
 		Phrase          bool
 		CaseSensitive   bool
 		Calc            bool
@@ -96,18 +129,36 @@ type (
 		HintText        string
 		HintImageBase64 string
 		HintImageFile   string
+		// Module is the CapMonster recognition module to use, e.g. "amazon",
+		// chosen automatically when empty.
+		Module string
+		// RecognizingThreshold is the confidence, 0 to 100, CapMonster must
+		// have in an answer before charging for it.
+		RecognizingThreshold int
 	}
 
 	ReCaptcha struct {
 		SiteKey   string
-		PageUrl       string
+		PageUrl   string
 		Invisible bool
 		Version   string
 		Action    string
 		Score     float64
 		UserAgent string
+		// Enterprise marks the site as using reCAPTCHA Enterprise.
+		Enterprise bool
+		// S is the data-s value some Enterprise widgets are rendered with.
+		S string
+		// EnterprisePayload holds any other parameters the Enterprise widget
+		// is rendered with.
+		EnterprisePayload map[string]string
+		// ApiDomain is the domain the widget is loaded from when it is not
+		// www.google.com, e.g. www.recaptcha.net.
+		ApiDomain string
 	}
 
+	// Rotate is an image to be rotated upright, or several when Files is
+	// set. Angle is the step in degrees the image can be rotated by.
 	Rotate struct {
 		File            string
 		Files           []string
@@ -122,9 +173,225 @@ type (
 		Text string
 		Lang string
 	}
+
+	// Turnstile is a Cloudflare Turnstile widget. Action and CData are the
+	// data-action and data-cdata attributes of the widget, if set.
+	Turnstile struct {
+		SiteKey string
+		PageUrl string
+		Action  string
+		CData   string
+		// PageData is the chlPageData of a Cloudflare challenge page. Setting
+		// it solves the challenge page variant, which also needs UserAgent.
+		PageData  string
+		UserAgent string
+	}
 )
 
+// Solution is a solved captcha as returned by Client.Solve. Token is always
+// set; the remaining fields are filled in when the provider reports them.
+type Solution struct {
+	// Token is the answer to submit to the site, e.g. the g-recaptcha-response
+	// or the text of an image captcha. Structured answers such as GeeTest's
+	// are given as JSON, and decoded into the typed fields below.
+	Token string
+	// TaskID is the provider's id for the task, used when reporting the result.
+	TaskID   string
+	Provider string
+	// Cost is the price charged for the task in USD.
+	Cost     float64
+	Duration time.Duration
+	// UserAgent is the user agent the worker solved the captcha with. Sites
+	// that validate it expect the token to be submitted with the same one.
+	UserAgent string
+	// RespKey is the hCaptcha response key.
+	RespKey string
+	Cookies map[string]string
+
+	GeeTest   *GeeTestSolution
+	GeeTestV4 *GeeTestV4Solution
+	Capy      *CapySolution
+	// Cells are the 1-based indexes of the Grid cells to click, numbered left
+	// to right and top to bottom. It is empty when no cell matches.
+	Cells []int
+	// Points are the positions to click, in order, for Coordinates.
+	Points []Point
+	// Lines are the lines to draw for a Canvas captcha, each a list of
+	// points to drag through.
+	Lines [][]Point
+	// Angle is the rotation in degrees for a Rotate captcha, and Angles the
+	// rotation of each image when several were sent.
+	Angle  int
+	Angles []int
+}
+
+// GeeTestSolution holds the three values a GeeTest v3 form is submitted with.
+type GeeTestSolution struct {
+	Challenge string `json:"geetest_challenge"`
+	Validate  string `json:"geetest_validate"`
+	Seccode   string `json:"geetest_seccode"`
+}
+
+// GeeTestV4Solution holds the values a GeeTest v4 form is submitted with.
+type GeeTestV4Solution struct {
+	CaptchaID     string `json:"captcha_id"`
+	LotNumber     string `json:"lot_number"`
+	PassToken     string `json:"pass_token"`
+	GenTime       string `json:"gen_time"`
+	CaptchaOutput string `json:"captcha_output"`
+}
+
+// CapySolution holds the values a Capy puzzle form is submitted with.
+type CapySolution struct {
+	CaptchaKey   string `json:"captchakey"`
+	ChallengeKey string `json:"challengekey"`
+	Answer       string `json:"answer"`
+}
+
+// Point is a position on a captcha image, in pixels from the top left corner.
+type Point struct {
+	X int
+	Y int
+}
+
 type Client interface {
-	Solve(captcha interface{}, proxy string) (string, error)
+	Solve(captcha interface{}, proxy string) (*Solution, error)
+	// SolveContext is like Solve, but stops polling and aborts any in-flight
+	// request once ctx is done, returning ctx.Err().
+	SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error)
 	GetBalance() (float64, error)
+	GetBalanceContext(ctx context.Context) (float64, error)
+}
+
+// TaskClient is a Client whose tasks can be submitted and polled separately.
+// Every provider client in this package implements it.
+type TaskClient interface {
+	Client
+	// SendContext submits the captcha and returns the provider's task id.
+	SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error)
+	// GetResContext returns the solution for the task, or ErrCaptchaNotReady
+	// while it is still being solved.
+	GetResContext(ctx context.Context, id string) (*Solution, error)
+}
+
+// solutionDecoder is implemented by TaskClients whose GetResContext leaves
+// typed results in Token, as the answer alone doesn't tell which captcha it
+// solves. decode fills in the fields of sol specific to captcha.
+type solutionDecoder interface {
+	decode(captcha interface{}, sol *Solution) error
+}
+
+// Reporter is implemented by clients whose provider accepts reports on
+// whether a solution was accepted by the site.
+type Reporter interface {
+	ReportContext(ctx context.Context, id string, correct bool) error
+}
+
+// totalBalance returns the sum of the balances of clients. If any client
+// fails, the sum of the others is returned along with the first error.
+func totalBalance(ctx context.Context, clients ...Client) (float64, error) {
+	var (
+		total    float64
+		firstErr error
+	)
+	for _, c := range clients {
+		bal, err := c.GetBalanceContext(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		total += bal
+	}
+	return total, firstErr
+}
+
+// statusError returns ErrNetwork for a response with a non-2xx status that
+// is not an answer from the service, such as a 502 page from a proxy.
+func statusError(resp *http.Response) error {
+	return fmt.Errorf("%w: %s", ErrNetwork, resp.Status)
+}
+
+// sleep pauses for d, returning early with ctx.Err() if ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// poll waits first, then calls getRes until it returns a solution or an
+// error other than ErrCaptchaNotReady, waiting interval between calls. Once
+// ctx is done it returns ctx.Err().
+func poll(ctx context.Context, first, interval time.Duration, getRes func() (*Solution, error)) (*Solution, error) {
+	if err := sleep(ctx, first); err != nil {
+		return nil, err
+	}
+	for {
+		solution, err := getRes()
+		if err == nil {
+			return solution, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !errors.Is(err, ErrCaptchaNotReady) {
+			return nil, err
+		}
+		if err := sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// imageBase64 returns b64 if it is set, otherwise the base64 encoded contents
+// of file.
+func imageBase64(file, b64 string) (string, error) {
+	if b64 != "" {
+		return b64, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// normalBase64 returns the base64 encoded image of c, from whichever of
+// Base64, Image, Reader or File is set.
+func normalBase64(c Normal) (string, error) {
+	switch {
+	case c.Base64 != "":
+		return c.Base64, nil
+	case c.Image != nil:
+		return base64.StdEncoding.EncodeToString(c.Image), nil
+	case c.Reader != nil:
+		data, err := io.ReadAll(c.Reader)
+		if err != nil {
+			return "", err
+		}
+		return base64.StdEncoding.EncodeToString(data), nil
+	default:
+		return imageBase64(c.File, "")
+	}
+}
+
+// readImage returns captcha with the Reader of a Normal read into Image, so
+// it can be submitted more than once.
+func readImage(captcha interface{}) (interface{}, error) {
+	c, ok := captcha.(Normal)
+	if !ok || c.Reader == nil || c.Base64 != "" || c.Image != nil {
+		return captcha, nil
+	}
+	data, err := io.ReadAll(c.Reader)
+	if err != nil {
+		return nil, err
+	}
+	c.Image, c.Reader = data, nil
+	return c, nil
 }
@@ -0,0 +1,350 @@
+package captchaAIO
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewDeathByCaptchaClient returns a client authenticating with the account's
+// username and password.
+func NewDeathByCaptchaClient(username, password string) *DeathByCaptcha {
+	return &DeathByCaptcha{
+		Username:     username,
+		Password:     password,
+		BaseURL:      deathByCaptchaURL,
+		FirstPoll:    time.Duration(5) * time.Second,
+		PollInterval: time.Duration(3) * time.Second,
+		http:         &http.Client{},
+	}
+}
+
+// NewDeathByCaptchaTokenClient returns a client authenticating with an
+// authtoken generated in the DeathByCaptcha panel.
+func NewDeathByCaptchaTokenClient(authToken string) *DeathByCaptcha {
+	return &DeathByCaptcha{
+		AuthToken:    authToken,
+		BaseURL:      deathByCaptchaURL,
+		FirstPoll:    time.Duration(5) * time.Second,
+		PollInterval: time.Duration(3) * time.Second,
+		http:         &http.Client{},
+	}
+}
+
+const deathByCaptchaURL = "https://api.dbcapi.me/api"
+
+// DeathByCaptcha is a client for deathbycaptcha.com. AuthToken is used when
+// set, otherwise the client authenticates with Username and Password.
+type DeathByCaptcha struct {
+	Username  string
+	Password  string
+	AuthToken string
+	// BaseURL is the address of the API, https://api.dbcapi.me/api unless
+	// pointed at a fake.
+	BaseURL string
+	// FirstPoll is how long Solve waits before polling a task for the first
+	// time and PollInterval how long it waits between polls.
+	FirstPoll    time.Duration
+	PollInterval time.Duration
+	http         *http.Client
+}
+
+func (dbc *DeathByCaptcha) logf(f string, v ...interface{}) {
+	if debug {
+		log.Printf(f, v...)
+	}
+}
+
+func (dbc *DeathByCaptcha) SetTimeout(t time.Duration) {
+	dbc.http.Timeout = t
+}
+
+func (dbc *DeathByCaptcha) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return dbc.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext uploads the captcha and polls for its result until it is
+// solved, the service returns an error, or ctx is done.
+func (dbc *DeathByCaptcha) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	dbc.logf("Starting DeathByCaptcha")
+	start := time.Now()
+	id, err := dbc.SendContext(ctx, captcha, proxy)
+	if err != nil {
+		return nil, err
+	}
+	dbc.logf("Solving captcha: %v", id)
+	solution, err := poll(ctx, dbc.FirstPoll, dbc.PollInterval, func() (*Solution, error) {
+		return dbc.GetResContext(ctx, id)
+	})
+	if err != nil {
+		dbc.logf(err.Error())
+		return nil, err
+	}
+	solution.Duration = time.Since(start)
+	return solution, nil
+}
+
+// Send uploads a captcha to DeathByCaptcha, will return the id of the
+// captcha or an error
+func (dbc *DeathByCaptcha) Send(captcha interface{}, proxy string) (string, error) {
+	return dbc.SendContext(context.Background(), captcha, proxy)
+}
+
+// SendContext is like Send, but aborts the upload once ctx is done.
+func (dbc *DeathByCaptcha) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	var (
+		form url.Values
+		err  error
+	)
+	switch t := captcha.(type) {
+	case Normal:
+		form, err = dbc.normal(t)
+	case ReCaptcha:
+		form, err = dbc.reCaptcha(t, proxy)
+	case HCaptcha:
+		form, err = dbc.hCaptcha(t, proxy)
+	case FunCaptcha:
+		form, err = dbc.funCaptcha(t, proxy)
+	default:
+		return "", ErrUnsupportedCaptcha
+	}
+	if err != nil {
+		return "", err
+	}
+
+	res, err := dbc.do(ctx, "POST", "/captcha", form)
+	if err != nil {
+		return "", err
+	}
+	if res.Captcha == 0 {
+		return "", ErrUnknown
+	}
+	return strconv.Itoa(res.Captcha), nil
+}
+
+// GetRes fetches the result of the captcha with the given id, returning
+// ErrCaptchaNotReady while a worker is still solving it.
+func (dbc *DeathByCaptcha) GetRes(id string) (*Solution, error) {
+	return dbc.GetResContext(context.Background(), id)
+}
+
+// GetResContext is like GetRes, but aborts the request once ctx is done.
+func (dbc *DeathByCaptcha) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	res, err := dbc.do(ctx, "GET", "/captcha/"+id, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !res.IsCorrect {
+		return nil, ErrCaptchaUnsolvable
+	}
+	if res.Text == "" {
+		return nil, ErrCaptchaNotReady
+	}
+	return &Solution{
+		Token:    res.Text,
+		TaskID:   id,
+		Provider: "deathbycaptcha",
+	}, nil
+}
+
+// Report reports the captcha with the given id as incorrectly solved.
+// DeathByCaptcha has no way of reporting correct answers, so Report is a
+// no-op when correct is true.
+func (dbc *DeathByCaptcha) Report(id string, correct bool) error {
+	return dbc.ReportContext(context.Background(), id, correct)
+}
+
+// ReportContext is like Report, but aborts the request once ctx is done.
+func (dbc *DeathByCaptcha) ReportContext(ctx context.Context, id string, correct bool) error {
+	if correct {
+		return nil
+	}
+	_, err := dbc.do(ctx, "POST", "/captcha/"+id+"/report", url.Values{})
+	return err
+}
+
+// GetBalance returns the account balance in USD. DeathByCaptcha reports
+// balances in US cents.
+func (dbc *DeathByCaptcha) GetBalance() (float64, error) {
+	return dbc.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext is like GetBalance, but aborts the request once ctx is done.
+func (dbc *DeathByCaptcha) GetBalanceContext(ctx context.Context) (float64, error) {
+	res, err := dbc.do(ctx, "POST", "/user", url.Values{})
+	if err != nil {
+		return 0, err
+	}
+	if res.IsBanned {
+		return 0, ErrIPBanned
+	}
+	return res.Balance / 100, nil
+}
+
+type deathByCaptchaResponse struct {
+	Status    int     `json:"status"`
+	Error     string  `json:"error"`
+	Captcha   int     `json:"captcha"`
+	Text      string  `json:"text"`
+	IsCorrect bool    `json:"is_correct"`
+	Balance   float64 `json:"balance"`
+	IsBanned  bool    `json:"is_banned"`
+}
+
+// do sends an API request, adding the account credentials to form when it is
+// not nil, and decodes the JSON response.
+func (dbc *DeathByCaptcha) do(ctx context.Context, method, path string, form url.Values) (*deathByCaptchaResponse, error) {
+	var body io.Reader
+	if form != nil {
+		if dbc.AuthToken != "" {
+			form.Set("authtoken", dbc.AuthToken)
+		} else {
+			form.Set("username", dbc.Username)
+			form.Set("password", dbc.Password)
+		}
+		body = strings.NewReader(form.Encode())
+	}
+	req, err := http.NewRequestWithContext(ctx, method, dbc.BaseURL+path, body)
+	if err != nil {
+		return nil, ErrNetwork
+	}
+	req.Header.Set("accept", "application/json")
+	if form != nil {
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	}
+	resp, err := dbc.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	dbc.logf("%v %v returned %v %s", method, path, resp.StatusCode, data)
+
+	var res deathByCaptchaResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, deathByCaptchaStatusError(resp)
+		}
+		return nil, err
+	}
+	if res.Error != "" {
+		return nil, deathByCaptchaError(res.Error)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, deathByCaptchaStatusError(resp)
+	}
+	return &res, nil
+}
+
+func (*DeathByCaptcha) normal(c Normal) (url.Values, error) {
+	body, err := normalBase64(c)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	form.Set("captchafile", "base64:"+body)
+	return form, nil
+}
+
+func (*DeathByCaptcha) reCaptcha(c ReCaptcha, proxy string) (url.Values, error) {
+	params := map[string]interface{}{
+		"googlekey": c.SiteKey,
+		"pageurl":   c.PageUrl,
+	}
+	form := url.Values{}
+	if c.Version == "3" {
+		form.Set("type", "5")
+		params["action"] = c.Action
+		params["min_score"] = c.Score
+	} else {
+		form.Set("type", "4")
+	}
+	return form, deathByCaptchaParams(form, "token_params", params, proxy)
+}
+
+func (*DeathByCaptcha) hCaptcha(c HCaptcha, proxy string) (url.Values, error) {
+	params := map[string]interface{}{
+		"sitekey": c.SiteKey,
+		"pageurl": c.PageUrl,
+	}
+	form := url.Values{}
+	form.Set("type", "7")
+	return form, deathByCaptchaParams(form, "hcaptcha_params", params, proxy)
+}
+
+func (*DeathByCaptcha) funCaptcha(c FunCaptcha, proxy string) (url.Values, error) {
+	params := map[string]interface{}{
+		"publickey": c.SiteKey,
+		"pageurl":   c.Url,
+	}
+	form := url.Values{}
+	form.Set("type", "6")
+	return form, deathByCaptchaParams(form, "funcaptcha_params", params, proxy)
+}
+
+// deathByCaptchaParams adds the proxy to params and stores them JSON encoded
+// in form under name, as DeathByCaptcha expects for token captchas.
+func deathByCaptchaParams(form url.Values, name string, params map[string]interface{}, proxy string) error {
+	if proxy != "" {
+		u, err := url.Parse(proxy)
+		if err != nil {
+			return err
+		}
+		params["proxy"] = proxy
+		params["proxytype"] = strings.ToUpper(u.Scheme)
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	form.Set(name, string(data))
+	return nil
+}
+
+// deathByCaptchaError maps an error returned by the DeathByCaptcha API to
+// one of the package's errors.
+func deathByCaptchaError(code string) error {
+	switch code {
+	case "not-logged-in", "invalid-credentials":
+		return ErrKeyDoesNotExist
+	case "banned":
+		return ErrIPBanned
+	case "insufficient-funds":
+		return ErrZeroBalance
+	case "invalid-captcha":
+		return ErrImageTypeNotSupported
+	case "service-overload":
+		return ErrNoSlotAvailable
+	default:
+		return ErrUnknown
+	}
+}
+
+// deathByCaptchaStatusError maps the HTTP status of a failed request without
+// a JSON body to one of the package's errors, ErrNetwork for statuses the API
+// does not document.
+func deathByCaptchaStatusError(resp *http.Response) error {
+	switch resp.StatusCode {
+	case http.StatusForbidden:
+		return ErrKeyDoesNotExist
+	case http.StatusBadRequest:
+		return ErrBadParameters
+	case http.StatusRequestEntityTooLarge:
+		return ErrTooBigCaptcha
+	case http.StatusServiceUnavailable:
+		return ErrNoSlotAvailable
+	case http.StatusNotFound:
+		return ErrNoSuchCaptchaID
+	default:
+		return statusError(resp)
+	}
+}
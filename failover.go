@@ -0,0 +1,82 @@
+package captchaAIO
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+)
+
+// NewFailover returns a Failover trying clients in the given order.
+func NewFailover(clients ...Client) *Failover {
+	return &Failover{
+		Clients: clients,
+	}
+}
+
+// Failover is a Client that solves captchas with the first of Clients, moving
+// on to the next one when a provider fails in a way another provider may not,
+// such as running out of funds or being unable to solve the captcha.
+type Failover struct {
+	Clients []Client
+}
+
+func (f *Failover) logf(format string, v ...interface{}) {
+	if debug {
+		log.Printf(format, v...)
+	}
+}
+
+func (f *Failover) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return f.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext tries each client in order, returning the first solution or
+// the error of the last client tried.
+func (f *Failover) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	captcha, err := readImage(captcha)
+	if err != nil {
+		return nil, err
+	}
+	err = ErrUnsupportedCaptcha
+	for i, c := range f.Clients {
+		var sol *Solution
+		sol, err = c.SolveContext(ctx, captcha, proxy)
+		if err == nil {
+			return sol, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if !shouldFailover(err) {
+			return nil, err
+		}
+		f.logf("client %v failed with %v, trying next", i, err)
+	}
+	return nil, err
+}
+
+func (f *Failover) GetBalance() (float64, error) {
+	return f.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext returns the sum of the balances of all clients. If any
+// client fails, the sum of the others is returned along with the first error.
+func (f *Failover) GetBalanceContext(ctx context.Context) (float64, error) {
+	return totalBalance(ctx, f.Clients...)
+}
+
+// shouldFailover reports whether err is worth retrying with another provider.
+func shouldFailover(err error) bool {
+	switch {
+	case errors.Is(err, ErrZeroBalance),
+		errors.Is(err, ErrNoSlotAvailable),
+		errors.Is(err, ErrCaptchaUnsolvable),
+		errors.Is(err, ErrUnsupportedCaptcha),
+		errors.Is(err, ErrIPBanned),
+		errors.Is(err, ErrNetwork):
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,171 @@
+package captchaAIO
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zMrKrabz/captcha-aio/captchatest"
+)
+
+// stubClient is a Client returning canned results without touching the network
+type stubClient struct {
+	solution *Solution
+	err      error
+	balance  float64
+	calls    int32
+}
+
+func (s *stubClient) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return s.SolveContext(context.Background(), captcha, proxy)
+}
+
+func (s *stubClient) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return s.solution, s.err
+}
+
+func (s *stubClient) GetBalance() (float64, error) {
+	return s.GetBalanceContext(context.Background())
+}
+
+func (s *stubClient) GetBalanceContext(ctx context.Context) (float64, error) {
+	return s.balance, s.err
+}
+
+// TestFailover_Type tests whether Failover implements the Client interface
+// fastPoll makes a provider client poll its fake server without waiting
+func fastPoll(firstPoll, interval *time.Duration) {
+	*firstPoll, *interval = 0, time.Millisecond
+}
+
+func TestFailover_Type(t *testing.T) {
+	var _ Client = &Failover{}
+	var _ Client = NewFailover()
+}
+
+func TestFailover_Solve(t *testing.T) {
+	broke := &stubClient{err: ErrZeroBalance}
+	ok := &stubClient{solution: &Solution{Token: "token"}}
+	unused := &stubClient{solution: &Solution{Token: "unused"}}
+	f := NewFailover(broke, ok, unused)
+
+	sol, err := f.Solve(HCaptcha{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "token" {
+		t.Errorf("got token %v, want token", sol.Token)
+	}
+	if broke.calls != 1 || ok.calls != 1 || unused.calls != 0 {
+		t.Errorf("unexpected calls %v %v %v", broke.calls, ok.calls, unused.calls)
+	}
+}
+
+func TestFailover_SolveStopsOnFatalError(t *testing.T) {
+	bad := &stubClient{err: ErrWrongUserKey}
+	next := &stubClient{solution: &Solution{Token: "token"}}
+	f := NewFailover(bad, next)
+
+	if _, err := f.Solve(HCaptcha{}, ""); !errors.Is(err, ErrWrongUserKey) {
+		t.Fatalf("expected ErrWrongUserKey, got %v", err)
+	}
+	if next.calls != 0 {
+		t.Errorf("next client should not have been called")
+	}
+
+	f = NewFailover(&stubClient{err: ErrNoSlotAvailable}, &stubClient{err: ErrCaptchaUnsolvable})
+	if _, err := f.Solve(HCaptcha{}, ""); !errors.Is(err, ErrCaptchaUnsolvable) {
+		t.Fatalf("expected ErrCaptchaUnsolvable, got %v", err)
+	}
+}
+
+func TestFailover_GetBalance(t *testing.T) {
+	f := NewFailover(&stubClient{balance: 1.5}, &stubClient{balance: 2}, &stubClient{err: ErrKeyDoesNotExist})
+	bal, err := f.GetBalance()
+	if !errors.Is(err, ErrKeyDoesNotExist) {
+		t.Errorf("expected ErrKeyDoesNotExist, got %v", err)
+	}
+	if bal != 3.5 {
+		t.Errorf("got balance %v, want 3.5", bal)
+	}
+}
+
+// TestFailover_SolveUnavailableProvider tests that a provider answering with
+// an error page is skipped
+func TestFailover_SolveUnavailableProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		fmt.Fprint(w, "<html><body><h1>502 Bad Gateway</h1></body></html>")
+	}))
+	defer srv.Close()
+	twoCaptcha := NewTwoCaptchaClient("key")
+	twoCaptcha.BaseURL = srv.URL
+	capMonster := NewCapMonsterClient("key")
+	capMonster.BaseURL = srv.URL
+	deathByCaptcha := NewDeathByCaptchaTokenClient("token")
+	deathByCaptcha.BaseURL = srv.URL
+
+	if _, err := twoCaptcha.Send(HCaptcha{}, ""); !errors.Is(err, ErrNetwork) {
+		t.Errorf("2captcha send: expected ErrNetwork, got %v", err)
+	}
+	if _, err := twoCaptcha.GetRes("1"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("2captcha result: expected ErrNetwork, got %v", err)
+	}
+	if _, err := twoCaptcha.GetBalance(); !errors.Is(err, ErrNetwork) {
+		t.Errorf("2captcha balance: expected ErrNetwork, got %v", err)
+	}
+	if _, err := capMonster.GetRes("1"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("capmonster result: expected ErrNetwork, got %v", err)
+	}
+	if _, err := deathByCaptcha.Send(HCaptcha{}, ""); !errors.Is(err, ErrNetwork) {
+		t.Errorf("deathbycaptcha send: expected ErrNetwork, got %v", err)
+	}
+	if _, err := deathByCaptcha.GetRes("1"); !errors.Is(err, ErrNetwork) {
+		t.Errorf("deathbycaptcha result: expected ErrNetwork, got %v", err)
+	}
+	if _, err := deathByCaptcha.GetBalance(); !errors.Is(err, ErrNetwork) {
+		t.Errorf("deathbycaptcha balance: expected ErrNetwork, got %v", err)
+	}
+
+	ok := &stubClient{solution: &Solution{Token: "token"}}
+	sol, err := NewFailover(twoCaptcha, capMonster, deathByCaptcha, ok).Solve(HCaptcha{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "token" || ok.calls != 1 {
+		t.Errorf("expected the working client to solve the captcha, got %+v", sol)
+	}
+}
+
+// TestFailover_SolveReader tests that an image read from a Reader reaches
+// every provider tried, not only the first
+func TestFailover_SolveReader(t *testing.T) {
+	twoCaptchaSrv, twoCaptcha := newTwoCaptchaFake(t)
+	twoCaptchaSrv.Push(captchatest.Script{SubmitError: "ERROR_NO_SLOT_AVAILABLE"})
+	capMonsterSrv, capMonster := newCapMonsterFake(t)
+	capMonsterSrv.Push(captchatest.Script{Answer: map[string]string{"text": "w9h5k"}})
+
+	image := []byte("GIF89a\x01\x00\x01\x00")
+	sol, err := NewFailover(twoCaptcha, capMonster).Solve(Normal{Reader: bytes.NewReader(image)}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "w9h5k" {
+		t.Errorf("got token %v, want w9h5k", sol.Token)
+	}
+	encoded := base64.StdEncoding.EncodeToString(image)
+	if body := twoCaptchaSrv.Submissions()[0].Params.Get("body"); body != encoded {
+		t.Errorf("2captcha got body %q, want %q", body, encoded)
+	}
+	if body := capMonsterSrv.Tasks()[0]["body"]; body != encoded {
+		t.Errorf("capmonster got body %q, want %q", body, encoded)
+	}
+}
@@ -0,0 +1,221 @@
+package captchaAIO
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultTokenLifetime is how long a pooled reCAPTCHA or hCaptcha token is
+// handed out for. Both are valid for about two minutes after being solved.
+const DefaultTokenLifetime = 110 * time.Second
+
+// ErrPoolSize is returned by TokenPool.Run when Size is not positive.
+var ErrPoolSize = errors.New("captchaAIO: token pool size must be positive")
+
+// NewTokenPool returns a TokenPool keeping size tokens for captcha warm.
+func NewTokenPool(client Client, captcha interface{}, size int) *TokenPool {
+	return &TokenPool{
+		Client:   client,
+		Captcha:  captcha,
+		Size:     size,
+		Lifetime: DefaultTokenLifetime,
+	}
+}
+
+// TokenPool continuously pre-solves a ReCaptcha or HCaptcha so tokens are
+// ready the moment they are needed, discarding them once they expire.
+type TokenPool struct {
+	Client  Client
+	Captcha interface{}
+	Proxy   string
+	// Size is the number of tokens kept solved or being solved at once.
+	Size int
+	// Lifetime is how long a token is handed out for after being solved,
+	// DefaultTokenLifetime if zero.
+	Lifetime time.Duration
+	// RampUp is how long before the release time given to RunAt the pool
+	// starts solving. It defaults to half of Lifetime, so the first tokens
+	// are ready at release and stay valid for a while after it.
+	RampUp time.Duration
+
+	mu      sync.Mutex
+	tokens  []pooledToken
+	pending int
+	// changed is closed and replaced whenever tokens or pending change, it is
+	// made on first use so a TokenPool literal works.
+	changed chan struct{}
+}
+
+type pooledToken struct {
+	solution *Solution
+	expires  time.Time
+}
+
+func (p *TokenPool) logf(format string, v ...interface{}) {
+	if debug {
+		log.Printf(format, v...)
+	}
+}
+
+// Run keeps the pool filled until ctx is done, then returns ctx.Err(). It
+// returns ErrUnsupportedCaptcha if the pool's captcha is not a ReCaptcha or
+// HCaptcha, and ErrPoolSize if Size is not positive.
+func (p *TokenPool) Run(ctx context.Context) error {
+	switch p.Captcha.(type) {
+	case ReCaptcha, HCaptcha:
+	default:
+		return ErrUnsupportedCaptcha
+	}
+	if p.Size <= 0 {
+		return ErrPoolSize
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < p.Size; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.work(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// RunAt waits until RampUp before release and then runs the pool like Run.
+func (p *TokenPool) RunAt(ctx context.Context, release time.Time) error {
+	rampUp := p.RampUp
+	if rampUp == 0 {
+		rampUp = p.lifetime() / 2
+	}
+	if err := sleep(ctx, time.Until(release.Add(-rampUp))); err != nil {
+		return err
+	}
+	return p.Run(ctx)
+}
+
+// work solves tokens whenever the pool has room for one.
+func (p *TokenPool) work(ctx context.Context) {
+	for {
+		p.mu.Lock()
+		p.prune()
+		if len(p.tokens)+p.pending >= p.Size {
+			wait := p.waitLocked()
+			p.mu.Unlock()
+			if err := wait(ctx); err != nil {
+				return
+			}
+			continue
+		}
+		p.pending++
+		p.mu.Unlock()
+
+		sol, err := p.Client.SolveContext(ctx, p.Captcha, p.Proxy)
+
+		p.mu.Lock()
+		p.pending--
+		if err == nil {
+			p.tokens = append(p.tokens, pooledToken{sol, time.Now().Add(p.lifetime())})
+		}
+		p.notify()
+		p.mu.Unlock()
+
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			p.logf("pool failed to solve token: %v, retrying in 5 seconds", err)
+			if sleep(ctx, time.Duration(5)*time.Second) != nil {
+				return
+			}
+		}
+	}
+}
+
+// Get returns the oldest unexpired token in the pool, waiting for one to be
+// solved if the pool is empty.
+func (p *TokenPool) Get(ctx context.Context) (*Solution, error) {
+	for {
+		p.mu.Lock()
+		p.prune()
+		if len(p.tokens) > 0 {
+			t := p.tokens[0]
+			p.tokens = p.tokens[1:]
+			p.notify()
+			p.mu.Unlock()
+			return t.solution, nil
+		}
+		wait := p.waitLocked()
+		p.mu.Unlock()
+		if err := wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Len returns the number of unexpired tokens in the pool.
+func (p *TokenPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prune()
+	return len(p.tokens)
+}
+
+// prune drops expired tokens, p.mu must be held.
+func (p *TokenPool) prune() {
+	now := time.Now()
+	i := 0
+	for i < len(p.tokens) && !now.Before(p.tokens[i].expires) {
+		i++
+	}
+	if i > 0 {
+		p.tokens = p.tokens[i:]
+		p.notify()
+	}
+}
+
+func (p *TokenPool) lifetime() time.Duration {
+	if p.Lifetime == 0 {
+		return DefaultTokenLifetime
+	}
+	return p.Lifetime
+}
+
+// notify wakes everything waiting on the pool, p.mu must be held.
+func (p *TokenPool) notify() {
+	if p.changed != nil {
+		close(p.changed)
+	}
+	p.changed = make(chan struct{})
+}
+
+// waitLocked returns a function blocking until the pool changes, the oldest
+// token expires, or ctx is done. p.mu must be held when calling waitLocked
+// but not when calling the returned function.
+func (p *TokenPool) waitLocked() func(ctx context.Context) error {
+	if p.changed == nil {
+		p.changed = make(chan struct{})
+	}
+	changed := p.changed
+	var timer *time.Timer
+	if len(p.tokens) > 0 {
+		timer = time.NewTimer(time.Until(p.tokens[0].expires))
+	}
+	return func(ctx context.Context) error {
+		var expiry <-chan time.Time
+		if timer != nil {
+			defer timer.Stop()
+			expiry = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-changed:
+		case <-expiry:
+		}
+		return nil
+	}
+}
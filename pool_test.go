@@ -0,0 +1,105 @@
+package captchaAIO
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTokenPool_Get(t *testing.T) {
+	client := &stubClient{solution: &Solution{Token: "token"}}
+	pool := NewTokenPool(client, ReCaptcha{}, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pool.Run(ctx)
+	}()
+
+	for i := 0; i < 5; i++ {
+		sol, err := pool.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sol.Token != "token" {
+			t.Errorf("got token %v, want token", sol.Token)
+		}
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if pool.Len() > 2 {
+		t.Errorf("pool holds %v tokens, more than its size", pool.Len())
+	}
+}
+
+func TestTokenPool_Expiry(t *testing.T) {
+	client := &stubClient{solution: &Solution{Token: "token"}}
+	pool := NewTokenPool(client, HCaptcha{}, 1)
+	pool.Lifetime = 10 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	pool.Run(ctx)
+
+	// Every token expired, so the pool kept replacing them
+	if calls := atomic.LoadInt32(&client.calls); calls < 3 {
+		t.Errorf("expected expired tokens to be replaced, solved %v", calls)
+	}
+}
+
+// TestTokenPool_Literal tests that a pool built without NewTokenPool works and
+// keeps its tokens for DefaultTokenLifetime
+func TestTokenPool_Literal(t *testing.T) {
+	client := &stubClient{solution: &Solution{Token: "token"}}
+	pool := &TokenPool{Client: client, Captcha: ReCaptcha{}, Size: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- pool.Run(ctx)
+	}()
+
+	sol, err := pool.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "token" {
+		t.Errorf("got token %v, want token", sol.Token)
+	}
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+	// One token was taken and one solved to replace it, neither expired
+	if calls := atomic.LoadInt32(&client.calls); calls != 2 {
+		t.Errorf("expected 2 solves, got %v", calls)
+	}
+}
+
+func TestTokenPool_Unsupported(t *testing.T) {
+	pool := NewTokenPool(&stubClient{}, Normal{}, 1)
+	if err := pool.Run(context.Background()); !errors.Is(err, ErrUnsupportedCaptcha) {
+		t.Errorf("expected ErrUnsupportedCaptcha, got %v", err)
+	}
+}
+
+func TestTokenPool_Size(t *testing.T) {
+	for _, size := range []int{0, -1} {
+		pool := NewTokenPool(&stubClient{}, ReCaptcha{}, size)
+		if err := pool.Run(context.Background()); !errors.Is(err, ErrPoolSize) {
+			t.Errorf("size %v: expected ErrPoolSize, got %v", size, err)
+		}
+	}
+}
+
+func TestTokenPool_RunAt(t *testing.T) {
+	client := &stubClient{solution: &Solution{Token: "token"}}
+	pool := NewTokenPool(client, ReCaptcha{}, 1)
+	pool.RampUp = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	pool.RunAt(ctx, time.Now().Add(time.Hour))
+	if calls := atomic.LoadInt32(&client.calls); calls != 0 {
+		t.Errorf("pool solved %v tokens before ramp up", calls)
+	}
+}
@@ -0,0 +1,122 @@
+package captchaAIO
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// NewRace returns a Race submitting every captcha to all of clients.
+func NewRace(clients ...TaskClient) *Race {
+	return &Race{
+		Clients: clients,
+	}
+}
+
+// Race is a Client that submits each captcha to several providers at once
+// and returns the first solution, trading cost for latency. Tasks that lose
+// the race are abandoned: polling stops, but the providers still charge for
+// them once solved.
+type Race struct {
+	Clients []TaskClient
+	// Max caps how many of Clients a captcha is submitted to, bounding the
+	// cost of a solve to Max times that of a single provider. Zero means all.
+	Max int
+	// Interval is how often tasks are polled for a result, 5 seconds if zero.
+	Interval time.Duration
+}
+
+func (r *Race) logf(format string, v ...interface{}) {
+	if debug {
+		log.Printf(format, v...)
+	}
+}
+
+func (r *Race) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return r.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext submits the captcha to the racing clients concurrently and
+// returns the first solution. If every client fails, the last error is
+// returned.
+func (r *Race) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	clients := r.Clients
+	if r.Max > 0 && r.Max < len(clients) {
+		clients = clients[:r.Max]
+	}
+	if len(clients) == 0 {
+		return nil, ErrUnsupportedCaptcha
+	}
+	captcha, err := readImage(captcha)
+	if err != nil {
+		return nil, err
+	}
+
+	raceCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		solution *Solution
+		err      error
+	}
+	start := time.Now()
+	results := make(chan result, len(clients))
+	for _, c := range clients {
+		go func(c TaskClient) {
+			sol, err := r.solve(raceCtx, c, captcha, proxy)
+			results <- result{sol, err}
+		}(c)
+	}
+
+	for range clients {
+		res := <-results
+		if res.err == nil {
+			res.solution.Duration = time.Since(start)
+			return res.solution, nil
+		}
+		r.logf("racing client failed with %v", res.err)
+		err = res.err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return nil, err
+}
+
+// solve sends the captcha to c and polls it until it is solved or ctx is done.
+func (r *Race) solve(ctx context.Context, c TaskClient, captcha interface{}, proxy string) (*Solution, error) {
+	id, err := c.SendContext(ctx, captcha, proxy)
+	if err != nil {
+		return nil, err
+	}
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Duration(5) * time.Second
+	}
+	sol, err := poll(ctx, interval, interval, func() (*Solution, error) {
+		return c.GetResContext(ctx, id)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := c.(solutionDecoder); ok {
+		if err := d.decode(captcha, sol); err != nil {
+			return nil, err
+		}
+	}
+	return sol, nil
+}
+
+func (r *Race) GetBalance() (float64, error) {
+	return r.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext returns the sum of the balances of all clients. If any
+// client fails, the sum of the others is returned along with the first error.
+func (r *Race) GetBalanceContext(ctx context.Context) (float64, error) {
+	clients := make([]Client, len(r.Clients))
+	for i, c := range r.Clients {
+		clients[i] = c
+	}
+	return totalBalance(ctx, clients...)
+}
@@ -0,0 +1,142 @@
+package captchaAIO
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/zMrKrabz/captcha-aio/captchatest"
+)
+
+// stubTaskClient is a TaskClient whose tasks become ready after a number of polls
+type stubTaskClient struct {
+	stubClient
+	polls int32
+	ready int32
+}
+
+func (s *stubTaskClient) SendContext(ctx context.Context, captcha interface{}, proxy string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return "1", nil
+}
+
+func (s *stubTaskClient) GetResContext(ctx context.Context, id string) (*Solution, error) {
+	if atomic.AddInt32(&s.polls, 1) < s.ready {
+		return nil, ErrCaptchaNotReady
+	}
+	return s.solution, nil
+}
+
+// TestRace_Type tests whether Race and the providers implement the
+// Client and TaskClient interfaces
+func TestRace_Type(t *testing.T) {
+	var _ Client = &Race{}
+	var _ Client = NewRace()
+	var _ TaskClient = &TwoCaptcha{}
+	var _ TaskClient = &CapMonster{}
+	var _ TaskClient = &AntiCaptcha{}
+	var _ TaskClient = &CapSolver{}
+	var _ TaskClient = &DeathByCaptcha{}
+}
+
+func TestRace_Solve(t *testing.T) {
+	slow := &stubTaskClient{stubClient: stubClient{solution: &Solution{Token: "slow"}}, ready: 1000}
+	fast := &stubTaskClient{stubClient: stubClient{solution: &Solution{Token: "fast"}}, ready: 2}
+	r := NewRace(slow, fast)
+	r.Interval = time.Millisecond
+
+	sol, err := r.Solve(HCaptcha{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "fast" {
+		t.Errorf("got token %v, want fast", sol.Token)
+	}
+}
+
+func TestRace_SolveMax(t *testing.T) {
+	first := &stubTaskClient{stubClient: stubClient{err: ErrZeroBalance}}
+	second := &stubTaskClient{stubClient: stubClient{solution: &Solution{Token: "second"}}}
+	r := NewRace(first, second)
+	r.Max = 1
+	r.Interval = time.Millisecond
+
+	if _, err := r.Solve(HCaptcha{}, ""); !errors.Is(err, ErrZeroBalance) {
+		t.Fatalf("expected ErrZeroBalance, got %v", err)
+	}
+	if atomic.LoadInt32(&second.polls) != 0 {
+		t.Errorf("client past Max should not have been used")
+	}
+}
+
+func TestRace_SolveContextCanceled(t *testing.T) {
+	never := &stubTaskClient{stubClient: stubClient{solution: &Solution{}}, ready: 1 << 30}
+	r := NewRace(never)
+	r.Interval = time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := r.SolveContext(ctx, HCaptcha{}, ""); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRace_SolveDecodesTwoCaptcha(t *testing.T) {
+	srv, solver := newTwoCaptchaFake(t)
+	r := NewRace(solver)
+	r.Interval = time.Millisecond
+
+	geeTest := GeeTestSolution{Challenge: "1a2b3c4d5e6f", Validate: "f1e2d3c4b5a6", Seccode: "f1e2d3c4b5a6|jordan"}
+	srv.Push(captchatest.Script{Answer: geeTest})
+	sol, err := r.Solve(GeeTest{GT: "81388ea1fc187e0c335c0a8907ff2625", Challenge: "1a2b3c4d5e6f"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.GeeTest == nil || *sol.GeeTest != geeTest {
+		t.Errorf("got %+v, want %+v", sol.GeeTest, geeTest)
+	}
+
+	srv.Push(captchatest.Script{Answer: "click:1/4/7"})
+	if sol, err = r.Solve(Grid{Base64: "R0lGODlhAQABAAAAACw="}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sol.Cells, []int{1, 4, 7}) {
+		t.Errorf("got cells %v, want [1 4 7]", sol.Cells)
+	}
+
+	path := filepath.Join(t.TempDir(), "captcha.gif")
+	if err := os.WriteFile(path, []byte("GIF89a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv.Push(captchatest.Script{Answer: "40|140"})
+	if sol, err = r.Solve(Rotate{Files: []string{path, path}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if sol.Angle != 40 || !reflect.DeepEqual(sol.Angles, []int{40, 140}) {
+		t.Errorf("unexpected solution %+v", sol)
+	}
+}
+
+// TestRace_SolveCapSolverSync tests that a task CapSolver answered from
+// createTask is not polled for with getTaskResult, which does not know it
+func TestRace_SolveCapSolverSync(t *testing.T) {
+	srv, solver := newCapSolverFake(t)
+	r := NewRace(solver)
+	r.Interval = time.Millisecond
+
+	srv.Push(captchatest.Script{Sync: true, Answer: map[string]string{"text": "w9h5k"}})
+	sol, err := r.Solve(Normal{Base64: "R0lGODlhAQABAAAAACw="}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "w9h5k" || sol.Provider != "capsolver" {
+		t.Errorf("unexpected solution %+v", sol)
+	}
+}
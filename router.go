@@ -0,0 +1,248 @@
+package captchaAIO
+
+import (
+	"context"
+	"errors"
+	"log"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// routeAlpha is the weight of the latest solve in the rolling statistics.
+	routeAlpha = 0.2
+	// minRouteSuccess keeps a client whose solves all failed comparable to
+	// the others instead of ruling it out for good.
+	minRouteSuccess = 0.01
+	// minRouteCost is the least a solve is assumed to cost in USD, so that a
+	// failing client scores worse than a working one even when neither has
+	// a price or latency cost.
+	minRouteCost = 0.0001
+)
+
+// NewRouter returns a Router choosing between clients, keyed by the name they
+// are referred to by in prices and overrides.
+func NewRouter(clients map[string]Client) *Router {
+	return &Router{
+		Clients:   clients,
+		Prices:    map[string]map[string]float64{},
+		Overrides: map[string]string{},
+	}
+}
+
+// Router is a Client that sends each captcha to the provider expected to
+// solve it most cheaply, based on a price table and rolling success and
+// latency statistics from past solves. Solutions returned by a Router have
+// Provider set to the name of the client that solved them.
+type Router struct {
+	Clients map[string]Client
+	// Prices maps a captcha type, as returned by CaptchaType, to the price in
+	// USD each client charges for it. Missing prices are treated as zero.
+	Prices map[string]map[string]float64
+	// Overrides maps a captcha type to the name of the client that should
+	// always be used for it.
+	Overrides map[string]string
+	// LatencyCost is what one second of solve time is worth in USD. It lets
+	// a faster provider win over a cheaper one.
+	LatencyCost float64
+
+	mu    sync.Mutex
+	stats map[string]map[string]*RouteStats
+}
+
+// RouteStats are the rolling statistics a Router keeps per captcha type and
+// client.
+type RouteStats struct {
+	// Success is the exponentially weighted rate of solves that returned a
+	// token the site accepted.
+	Success float64
+	// Latency is the exponentially weighted solve time, including the time
+	// taken by failed solves.
+	Latency time.Duration
+	Solves  int
+	// Unsupported is set once the client rejects the captcha type.
+	Unsupported bool
+}
+
+func (r *Router) logf(format string, v ...interface{}) {
+	if debug {
+		log.Printf(format, v...)
+	}
+}
+
+// CaptchaType returns the name a captcha is routed by: its type name, with
+// reCAPTCHA v3 distinguished from v2 as "ReCaptchaV3".
+func CaptchaType(captcha interface{}) string {
+	if c, ok := captcha.(ReCaptcha); ok && c.Version == "3" {
+		return "ReCaptchaV3"
+	}
+	t := reflect.TypeOf(captcha)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Name()
+}
+
+func (r *Router) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return r.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext solves the captcha with the best client for its type and
+// records the outcome.
+func (r *Router) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	typ := CaptchaType(captcha)
+	name := r.Route(typ)
+	c, ok := r.Clients[name]
+	if !ok {
+		return nil, ErrUnsupportedCaptcha
+	}
+	r.logf("routing %v to %v", typ, name)
+
+	start := time.Now()
+	sol, err := c.SolveContext(ctx, captcha, proxy)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	r.mu.Lock()
+	st := r.routeStats(typ, name)
+	switch {
+	case errors.Is(err, ErrUnsupportedCaptcha):
+		st.Unsupported = true
+	case err != nil:
+		st.record(false, time.Since(start))
+	default:
+		st.record(true, time.Since(start))
+	}
+	r.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	sol.Provider = name
+	return sol, nil
+}
+
+// Route returns the name of the client a captcha of the given type would be
+// sent to, or "" if none can solve it.
+func (r *Router) Route(typ string) string {
+	if name, ok := r.Overrides[typ]; ok {
+		return name
+	}
+
+	names := make([]string, 0, len(r.Clients))
+	for name := range r.Clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	best, bestScore := "", 0.0
+	for _, name := range names {
+		st := r.routeStats(typ, name)
+		if st.Unsupported {
+			continue
+		}
+		score := r.score(r.Prices[typ][name], st)
+		if best == "" || score < bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
+// score is the expected cost of getting one accepted token from a client.
+// Clients without any solves are assumed to always succeed instantly, so
+// each gets tried.
+func (r *Router) score(price float64, st *RouteStats) float64 {
+	if st.Solves == 0 {
+		return price
+	}
+	success := st.Success
+	if success < minRouteSuccess {
+		success = minRouteSuccess
+	}
+	cost := price + r.LatencyCost*st.Latency.Seconds()
+	if cost < minRouteCost {
+		cost = minRouteCost
+	}
+	return cost / success
+}
+
+// Report records whether the site accepted sol, a solution to captcha
+// returned by this Router, and forwards the report to the provider if it
+// supports reporting.
+func (r *Router) Report(ctx context.Context, captcha interface{}, sol *Solution, correct bool) error {
+	typ := CaptchaType(captcha)
+	if !correct {
+		r.mu.Lock()
+		st := r.routeStats(typ, sol.Provider)
+		st.Success *= 1 - routeAlpha
+		r.mu.Unlock()
+	}
+	if rep, ok := r.Clients[sol.Provider].(Reporter); ok {
+		return rep.ReportContext(ctx, sol.TaskID, correct)
+	}
+	return nil
+}
+
+// Stats returns a copy of the statistics recorded for captchas of the given
+// type, keyed by client name.
+func (r *Router) Stats(typ string) map[string]RouteStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := map[string]RouteStats{}
+	for name, st := range r.stats[typ] {
+		stats[name] = *st
+	}
+	return stats
+}
+
+// routeStats returns the statistics for typ and name, r.mu must be held.
+func (r *Router) routeStats(typ, name string) *RouteStats {
+	if r.stats == nil {
+		r.stats = map[string]map[string]*RouteStats{}
+	}
+	if r.stats[typ] == nil {
+		r.stats[typ] = map[string]*RouteStats{}
+	}
+	st, ok := r.stats[typ][name]
+	if !ok {
+		st = &RouteStats{}
+		r.stats[typ][name] = st
+	}
+	return st
+}
+
+func (st *RouteStats) record(success bool, latency time.Duration) {
+	s := 0.0
+	if success {
+		s = 1
+	}
+	if st.Solves == 0 {
+		st.Success = s
+		st.Latency = latency
+	} else {
+		st.Success = routeAlpha*s + (1-routeAlpha)*st.Success
+		st.Latency = time.Duration(routeAlpha*float64(latency) + (1-routeAlpha)*float64(st.Latency))
+	}
+	st.Solves++
+}
+
+func (r *Router) GetBalance() (float64, error) {
+	return r.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext returns the sum of the balances of all clients. If any
+// client fails, the sum of the others is returned along with the first error.
+func (r *Router) GetBalanceContext(ctx context.Context) (float64, error) {
+	clients := make([]Client, 0, len(r.Clients))
+	for _, c := range r.Clients {
+		clients = append(clients, c)
+	}
+	return totalBalance(ctx, clients...)
+}
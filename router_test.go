@@ -0,0 +1,84 @@
+package captchaAIO
+
+import (
+	"testing"
+)
+
+// TestRouter_Type tests whether Router implements the Client interface
+func TestRouter_Type(t *testing.T) {
+	var _ Client = &Router{}
+	var _ Client = NewRouter(nil)
+}
+
+func TestCaptchaType(t *testing.T) {
+	tests := []struct {
+		captcha interface{}
+		want    string
+	}{
+		{ReCaptcha{}, "ReCaptcha"},
+		{ReCaptcha{Version: "3"}, "ReCaptchaV3"},
+		{HCaptcha{}, "HCaptcha"},
+		{&Normal{}, "Normal"},
+	}
+	for _, tt := range tests {
+		if got := CaptchaType(tt.captcha); got != tt.want {
+			t.Errorf("CaptchaType(%T) = %v, want %v", tt.captcha, got, tt.want)
+		}
+	}
+}
+
+func TestRouter_Solve(t *testing.T) {
+	cheap := &stubClient{err: ErrCaptchaUnsolvable}
+	pricey := &stubClient{solution: &Solution{Token: "token"}}
+	r := NewRouter(map[string]Client{"cheap": cheap, "pricey": pricey})
+	r.Prices["HCaptcha"] = map[string]float64{"cheap": 0.001, "pricey": 0.002}
+
+	if got := r.Route("HCaptcha"); got != "cheap" {
+		t.Fatalf("got route %v, want cheap", got)
+	}
+	if _, err := r.Solve(HCaptcha{}, ""); err == nil {
+		t.Fatal("expected cheap client to fail")
+	}
+	// After failing, cheap is expected to cost far more per accepted token
+	sol, err := r.Solve(HCaptcha{}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sol.Token != "token" || sol.Provider != "pricey" {
+		t.Errorf("unexpected solution %+v", sol)
+	}
+	if st := r.Stats("HCaptcha"); st["cheap"].Solves != 1 || st["pricey"].Success != 1 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	r.Overrides["HCaptcha"] = "cheap"
+	if got := r.Route("HCaptcha"); got != "cheap" {
+		t.Errorf("got route %v, want override cheap", got)
+	}
+}
+
+func TestRouter_SolveUnsupported(t *testing.T) {
+	r := NewRouter(map[string]Client{"a": &stubClient{err: ErrUnsupportedCaptcha}})
+	if _, err := r.Solve(Capy{}, ""); err == nil {
+		t.Fatal("expected an error")
+	}
+	if got := r.Route("Capy"); got != "" {
+		t.Errorf("got route %v, want none", got)
+	}
+}
+
+// TestRouter_SolveWithoutPrices tests that a failing client loses to a
+// working one when no prices are set
+func TestRouter_SolveWithoutPrices(t *testing.T) {
+	failing := &stubClient{err: ErrCaptchaUnsolvable}
+	working := &stubClient{solution: &Solution{Token: "token"}}
+	r := NewRouter(map[string]Client{"failing": failing, "working": working})
+	r.LatencyCost = 0.001
+
+	for i := 0; i < 5; i++ {
+		r.Solve(HCaptcha{}, "")
+	}
+	if failing.calls != 1 || working.calls != 4 {
+		t.Errorf("got %v failing and %v working calls, want 1 and 4", failing.calls, working.calls)
+	}
+}
@@ -0,0 +1,459 @@
+package captchaAIO
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TaskRequest is the body of a createTask request for services that speak
+// the Anti-Captcha JSON protocol, such as CapMonster and Anti-Captcha.
+type TaskRequest struct {
+	ClientKey string                 `json:"clientKey"`
+	Task      map[string]interface{} `json:"task"`
+	SoftID    int                    `json:"softId,omitempty"`
+	AppID     string                 `json:"appId,omitempty"`
+}
+
+// taskResponse is the union of the createTask, getTaskResult and getBalance
+// responses of the Anti-Captcha JSON protocol.
+type taskResponse struct {
+	ErrorID   int             `json:"errorId"`
+	ErrorCode string          `json:"errorCode"`
+	TaskID    json.RawMessage `json:"taskId"`
+	Status    string          `json:"status"`
+	Cost      json.Number     `json:"cost"`
+	Balance   float64         `json:"balance"`
+	Solution  struct {
+		GRecaptchaResponse string            `json:"gRecaptchaResponse"`
+		Text               string            `json:"text"`
+		Token              string            `json:"token"`
+		UserAgent          string            `json:"userAgent"`
+		RespKey            string            `json:"respKey"`
+		Cookies            map[string]string `json:"cookies"`
+		Challenge          string            `json:"challenge"`
+		Validate           string            `json:"validate"`
+		Seccode            string            `json:"seccode"`
+		Answer             json.RawMessage   `json:"answer"`
+		GeeTestV4Solution
+	} `json:"solution"`
+}
+
+// taskAPI is a service speaking the Anti-Captcha JSON protocol.
+type taskAPI struct {
+	http     *http.Client
+	base     string
+	provider string
+	// mapError maps the service's errorCode to one of the package's errors.
+	mapError func(code string) error
+}
+
+// post posts payload as JSON to the given method and decodes the response,
+// mapping a non-zero errorId to one of the package's errors.
+func (a taskAPI) post(ctx context.Context, method string, payload interface{}) (*taskResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", a.base+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return nil, ErrNetwork
+	}
+	req.Header.Set("content-type", "application/json")
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var res taskResponse
+	err = json.Unmarshal(data, &res)
+	if resp.StatusCode/100 != 2 && (err != nil || res.ErrorID == 0) {
+		return nil, statusError(resp)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if res.ErrorID != 0 {
+		return nil, a.mapError(res.ErrorCode)
+	}
+	return &res, nil
+}
+
+// createTask submits req and returns the response, which carries the id of
+// the created task and, for tasks solved synchronously, its solution.
+func (a taskAPI) createTask(ctx context.Context, req TaskRequest) (*taskResponse, error) {
+	return a.post(ctx, "createTask", req)
+}
+
+// getTaskResult fetches the result of task id, returning ErrCaptchaNotReady
+// while it is still being solved.
+func (a taskAPI) getTaskResult(ctx context.Context, key, id string) (*Solution, error) {
+	payload := map[string]interface{}{
+		"clientKey": key,
+		"taskId":    taskID(id),
+	}
+	res, err := a.post(ctx, "getTaskResult", payload)
+	if err != nil {
+		return nil, err
+	}
+	if res.Status != "ready" {
+		return nil, ErrCaptchaNotReady
+	}
+	return res.solution(id, a.provider), nil
+}
+
+// getBalance returns the balance of the account identified by key.
+func (a taskAPI) getBalance(ctx context.Context, key string) (float64, error) {
+	payload := map[string]interface{}{
+		"clientKey": key,
+	}
+	res, err := a.post(ctx, "getBalance", payload)
+	if err != nil {
+		return 0, err
+	}
+	return res.Balance, nil
+}
+
+// id returns the task id of a createTask response as a string.
+func (r *taskResponse) id() string {
+	var id string
+	if err := json.Unmarshal(r.TaskID, &id); err != nil {
+		// Most services return a numeric id rather than a string
+		id = string(r.TaskID)
+	}
+	return id
+}
+
+func (r *taskResponse) solution(id, provider string) *Solution {
+	sol := &Solution{
+		Token:     r.Solution.GRecaptchaResponse,
+		TaskID:    id,
+		Provider:  provider,
+		UserAgent: r.Solution.UserAgent,
+		RespKey:   r.Solution.RespKey,
+		Cookies:   r.Solution.Cookies,
+	}
+	if sol.Token == "" {
+		sol.Token = r.Solution.Text
+	}
+	if sol.Token == "" {
+		sol.Token = r.Solution.Token
+	}
+	if r.Solution.Challenge != "" {
+		sol.GeeTest = &GeeTestSolution{
+			Challenge: r.Solution.Challenge,
+			Validate:  r.Solution.Validate,
+			Seccode:   r.Solution.Seccode,
+		}
+	}
+	if r.Solution.LotNumber != "" {
+		v4 := r.Solution.GeeTestV4Solution
+		sol.GeeTestV4 = &v4
+	}
+	// Like 2Captcha, structured answers are also given as JSON in Token
+	if sol.Token == "" && sol.GeeTest != nil {
+		data, _ := json.Marshal(sol.GeeTest)
+		sol.Token = string(data)
+	}
+	if sol.Token == "" && sol.GeeTestV4 != nil {
+		data, _ := json.Marshal(sol.GeeTestV4)
+		sol.Token = string(data)
+	}
+	// Grid tasks answer with whether each cell matches
+	var cells []bool
+	if json.Unmarshal(r.Solution.Answer, &cells) == nil {
+		for i, ok := range cells {
+			if ok {
+				sol.Cells = append(sol.Cells, i+1)
+			}
+		}
+	}
+	if r.Cost != "" {
+		sol.Cost, _ = r.Cost.Float64()
+	}
+	return sol
+}
+
+// taskID converts an id returned by createTask back into the JSON value the
+// service expects: a number for numeric ids and a string otherwise.
+func taskID(id string) interface{} {
+	if n, err := strconv.Atoi(id); err == nil {
+		return n
+	}
+	return id
+}
+
+// setTaskProxy adds the proxy fields to task, or marks the task type as
+// proxyless when proxy is empty.
+func setTaskProxy(task map[string]interface{}, proxy string) error {
+	if proxy == "" {
+		task["type"] = task["type"].(string) + "Proxyless"
+		return nil
+	}
+	u, err := url.Parse(proxy)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return err
+	}
+	task["proxyType"] = u.Scheme
+	task["proxyAddress"] = u.Hostname()
+	task["proxyPort"] = port
+	if u.User != nil {
+		task["proxyLogin"] = u.User.Username()
+		if pass, ok := u.User.Password(); ok {
+			task["proxyPassword"] = pass
+		}
+	}
+	return nil
+}
+
+// reCaptchaTask builds a RecaptchaV2Task, RecaptchaV2EnterpriseTask or
+// RecaptchaV3TaskProxyless for c. V3 tasks are always solved proxyless. The
+// data-s value of an Enterprise widget is sent as enterprisePayload.s.
+func reCaptchaTask(c ReCaptcha, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"websiteURL": c.PageUrl,
+		"websiteKey": c.SiteKey,
+	}
+	switch c.Version {
+	case "3":
+		task["type"] = "RecaptchaV3TaskProxyless"
+		if c.Score != 0 {
+			task["minScore"] = c.Score
+		}
+		if c.Action != "" {
+			task["pageAction"] = c.Action
+		}
+		if c.Enterprise {
+			task["isEnterprise"] = true
+		}
+		if c.ApiDomain != "" {
+			task["apiDomain"] = c.ApiDomain
+		}
+		return task, nil
+	case "", "2":
+	default:
+		return nil, ErrUnsupportedCaptcha
+	}
+
+	if c.Enterprise {
+		task["type"] = "RecaptchaV2EnterpriseTask"
+		payload := map[string]string{}
+		for k, v := range c.EnterprisePayload {
+			payload[k] = v
+		}
+		if c.S != "" {
+			payload["s"] = c.S
+		}
+		if len(payload) > 0 {
+			task["enterprisePayload"] = payload
+		}
+	} else {
+		task["type"] = "RecaptchaV2Task"
+	}
+	if c.ApiDomain != "" {
+		task["apiDomain"] = c.ApiDomain
+	}
+	if c.Invisible {
+		task["isInvisible"] = true
+	}
+	if c.UserAgent != "" {
+		task["userAgent"] = c.UserAgent
+	}
+	return task, setTaskProxy(task, proxy)
+}
+
+// hCaptchaTask builds an HCaptchaTask for c, sending the rqdata of an
+// Enterprise widget as enterprisePayload.rqdata.
+func hCaptchaTask(c HCaptcha, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"type":       "HCaptchaTask",
+		"websiteURL": c.PageUrl,
+		"websiteKey": c.SiteKey,
+	}
+	if c.UserAgent != "" {
+		task["userAgent"] = c.UserAgent
+	}
+	if c.Invisible {
+		task["isInvisible"] = true
+	}
+	if c.RqData != "" {
+		task["enterprisePayload"] = map[string]string{"rqdata": c.RqData}
+	}
+	return task, setTaskProxy(task, proxy)
+}
+
+// cookieString joins cookies into a "name=value; name=value" string, sorted
+// by name.
+func cookieString(cookies map[string]string) string {
+	names := make([]string, 0, len(cookies))
+	for name := range cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + cookies[name]
+	}
+	return strings.Join(parts, "; ")
+}
+
+func funCaptchaTask(c FunCaptcha, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"type":             "FunCaptchaTask",
+		"websiteURL":       c.Url,
+		"websitePublicKey": c.SiteKey,
+	}
+	if c.Surl != "" {
+		task["funcaptchaApiJSSubdomain"] = c.Surl
+	}
+	if len(c.Data) > 0 {
+		data, err := json.Marshal(c.Data)
+		if err != nil {
+			return nil, err
+		}
+		task["data"] = string(data)
+	}
+	if c.UserAgent != "" {
+		task["userAgent"] = c.UserAgent
+	}
+	return task, setTaskProxy(task, proxy)
+}
+
+func geeTestTask(c GeeTest, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"type":       "GeeTestTask",
+		"websiteURL": c.Url,
+		"gt":         c.GT,
+		"challenge":  c.Challenge,
+	}
+	if c.ApiServer != "" {
+		task["geetestApiServerSubdomain"] = c.ApiServer
+	}
+	return task, setTaskProxy(task, proxy)
+}
+
+// geeTestV4Task builds a version 4 GeeTestTask, passing the captcha_id as gt
+// and the widget's other settings as initParameters.
+func geeTestV4Task(c GeeTestV4, proxy string) (map[string]interface{}, error) {
+	task := map[string]interface{}{
+		"type":       "GeeTestTask",
+		"websiteURL": c.Url,
+		"gt":         c.CaptchaID,
+		"version":    4,
+	}
+	if c.ApiServer != "" {
+		task["geetestApiServerSubdomain"] = c.ApiServer
+	}
+	params := map[string]string{}
+	for k, v := range c.Extra {
+		params[k] = v
+	}
+	if c.RiskType != "" {
+		params["riskType"] = c.RiskType
+	}
+	if len(params) > 0 {
+		task["initParameters"] = params
+	}
+	return task, setTaskProxy(task, proxy)
+}
+
+// imageToTextTask builds an ImageToTextTask for c.
+func imageToTextTask(c Normal) (map[string]interface{}, error) {
+	body, err := normalBase64(c)
+	if err != nil {
+		return nil, err
+	}
+	task := map[string]interface{}{
+		"type": "ImageToTextTask",
+		"body": body,
+	}
+	if c.Phrase {
+		task["phrase"] = true
+	}
+	if c.CaseSensitive {
+		task["case"] = true
+	}
+	if c.Calc {
+		task["math"] = true
+	}
+	// Only digits (1) and only letters (2) have an equivalent, the other
+	// 2captcha numeric modes put no restriction on the answer
+	if c.Numberic == 1 || c.Numberic == 2 {
+		task["numeric"] = c.Numberic
+	}
+	if c.MinLen != 0 {
+		task["minLength"] = c.MinLen
+	}
+	if c.MaxLen != 0 {
+		task["maxLength"] = c.MaxLen
+	}
+	if c.HintText != "" {
+		task["comment"] = c.HintText
+	}
+	return task, nil
+}
+
+// taskError maps an errorCode returned by a service speaking the
+// Anti-Captcha JSON protocol to one of the package's errors.
+func taskError(code string) error {
+	switch code {
+	case "CAPCHA_NOT_READY":
+		return ErrCaptchaNotReady
+	case "ERROR_KEY_DOES_NOT_EXIST":
+		return ErrKeyDoesNotExist
+	case "ERROR_WRONG_IP_NOT_ALLOWED", "ERROR_IP_NOT_ALLOWED":
+		return ErrIPNotAllowed
+	case "ERROR_NO_SLOT_AVAILABLE":
+		return ErrNoSlotAvailable
+	case "ERROR_ZERO_CAPTCHA_FILESIZE":
+		return ErrZeroCaptchaFilesize
+	case "ERROR_TOO_BIG_CAPTCHA_FILESIZE":
+		return ErrTooBigCaptcha
+	case "ERROR_IMAGE_TYPE_NOT_SUPPORTED":
+		return ErrImageTypeNotSupported
+	case "ERROR_ZERO_BALANCE":
+		return ErrZeroBalance
+	case "ERROR_CAPTCHA_UNSOLVABLE":
+		return ErrCaptchaUnsolvable
+	case "ERROR_NO_SUCH_CAPCHA_ID":
+		return ErrNoSuchCaptchaID
+	case "WRONG_CAPTCHA_ID":
+		return ErrWrongCaptchaID
+	case "ERROR_IP_BANNED", "ERROR_IP_BLOCKED":
+		return ErrIPBanned
+	case "ERROR_NO_SUCH_METHOD":
+		return ErrNoSuchMethod
+	case "ERROR_TOO_MANY_REQUESTS", "ERROR_TOO_MUCH_REQUESTS":
+		return ErrTooManyRequests
+	case "ERROR_TASK_ABSENT", "ERROR_INCORRECT_SESSION_DATA":
+		return ErrBadParameters
+	case "ERROR_TASK_NOT_SUPPORTED":
+		return ErrUnsupportedCaptcha
+	case "ERROR_RECAPTCHA_INVALID_SITEKEY":
+		return ErrGoogleKey
+	case "ERROR_RECAPTCHA_INVALID_DOMAIN":
+		return ErrBadTokenOrPageURL
+	case "ERROR_PROXY_CONNECT_REFUSED", "ERROR_PROXY_CONNECT_TIMEOUT", "ERROR_PROXY_READ_TIMEOUT",
+		"ERROR_PROXY_BANNED", "ERROR_PROXY_TRANSPARENT", "ERROR_PROXY_NOT_AUTHORISED":
+		return ErrProxyConnFail
+	case "ERROR_TOKEN_EXPIRED":
+		return ErrTokenExpired
+	default:
+		return ErrUnknown
+	}
+}
@@ -0,0 +1,78 @@
+package captchaAIO
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// NewTextCache returns a TextCache solving uncached captchas with client.
+func NewTextCache(client Client) *TextCache {
+	return &TextCache{
+		Client:  client,
+		answers: map[string]string{},
+	}
+}
+
+// TextCache is a Client that remembers the answers to Text captchas, so a
+// question that has been answered before, such as "what is 3 + 4", is
+// answered without paying for a task. Questions are matched ignoring case
+// and whitespace. Any other captcha is passed through to Client.
+type TextCache struct {
+	Client Client
+
+	mu      sync.Mutex
+	answers map[string]string
+}
+
+func (tc *TextCache) Solve(captcha interface{}, proxy string) (*Solution, error) {
+	return tc.SolveContext(context.Background(), captcha, proxy)
+}
+
+// SolveContext returns the cached answer to a Text captcha, or solves it
+// with Client and caches the answer.
+func (tc *TextCache) SolveContext(ctx context.Context, captcha interface{}, proxy string) (*Solution, error) {
+	c, ok := captcha.(Text)
+	if !ok {
+		return tc.Client.SolveContext(ctx, captcha, proxy)
+	}
+	key := textKey(c)
+	tc.mu.Lock()
+	answer, ok := tc.answers[key]
+	tc.mu.Unlock()
+	if ok {
+		return &Solution{Token: answer, Provider: "cache"}, nil
+	}
+
+	sol, err := tc.Client.SolveContext(ctx, captcha, proxy)
+	if err != nil {
+		return nil, err
+	}
+	tc.mu.Lock()
+	tc.answers[key] = sol.Token
+	tc.mu.Unlock()
+	return sol, nil
+}
+
+// Forget removes the cached answer to c, e.g. after the site rejected it.
+func (tc *TextCache) Forget(c Text) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+	delete(tc.answers, textKey(c))
+}
+
+func (tc *TextCache) GetBalance() (float64, error) {
+	return tc.GetBalanceContext(context.Background())
+}
+
+// GetBalanceContext returns the balance of Client.
+func (tc *TextCache) GetBalanceContext(ctx context.Context) (float64, error) {
+	return tc.Client.GetBalanceContext(ctx)
+}
+
+// textKey returns the cache key of c, its language and its question in
+// lower case with runs of whitespace collapsed.
+func textKey(c Text) string {
+	question := strings.ToLower(strings.Join(strings.Fields(c.Text), " "))
+	return c.Lang + "\x00" + question
+}
@@ -0,0 +1,42 @@
+package captchaAIO
+
+import (
+	"testing"
+)
+
+// TestTextCache_Type tests whether TextCache implements the Client interface
+func TestTextCache_Type(t *testing.T) {
+	var _ Client = NewTextCache(&stubClient{})
+}
+
+func TestTextCache_Solve(t *testing.T) {
+	client := &stubClient{solution: &Solution{Token: "7", Provider: "2captcha"}}
+	cache := NewTextCache(client)
+
+	for _, question := range []string{"What is 3 + 4?", "what is  3 + 4? "} {
+		sol, err := cache.Solve(Text{Text: question, Lang: "en"}, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sol.Token != "7" {
+			t.Errorf("got token %v, want 7", sol.Token)
+		}
+	}
+	if client.calls != 1 {
+		t.Errorf("got %v calls, want 1", client.calls)
+	}
+
+	if _, err := cache.Solve(Text{Text: "What is 3 + 4?", Lang: "de"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	cache.Forget(Text{Text: "What is 3 + 4?", Lang: "en"})
+	if _, err := cache.Solve(Text{Text: "What is 3 + 4?", Lang: "en"}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Solve(HCaptcha{}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if client.calls != 4 {
+		t.Errorf("got %v calls, want 4", client.calls)
+	}
+}